@@ -0,0 +1,114 @@
+package insconfig
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Report is the result of Check
+type Report struct {
+	// Files are the checked config files in merge order
+	Files []string
+	// Problems are all the problems found in config files and ENV, typed errors of this package
+	// like UnknownKeysError or MissingKeysError when the check got that far
+	Problems []error
+}
+
+// OK returns true if no problems are found
+func (r Report) OK() bool {
+	return len(r.Problems) == 0
+}
+
+func (r Report) String() string {
+	b := &strings.Builder{}
+	files := strings.Join(r.Files, ", ")
+	if files == "" {
+		files = "no config files"
+	}
+	if r.OK() {
+		fmt.Fprintf(b, "OK: %s\n", files)
+		return b.String()
+	}
+	fmt.Fprintf(b, "FAIL: %s\n", files)
+	for _, problem := range r.Problems {
+		fmt.Fprintf(b, "  - %v\n", problem)
+	}
+	return b.String()
+}
+
+// Check loads config files and ENV into configStruct the same way Load does, but doesn't stop on the first problem.
+// Problems of the config are returned in Report, error is returned for wrong params only
+func Check(params Params, configStruct interface{}) (Report, error) {
+	if params.EnvPrefix == "" {
+		return Report{}, errors.New("EnvPrefix should be defined")
+	}
+	if params.ConfigPathGetter == nil {
+		return Report{}, errors.New("ConfigPathGetter should be defined")
+	}
+	params.AllErrors = true
+	i := New(params)
+
+	var report Report
+	err := i.load(i.configPaths, configStruct)
+	for _, f := range i.files {
+		report.Files = append(report.Files, f.path)
+	}
+	report.Problems = flattenErrors(err)
+	return report, nil
+}
+
+// flattenErrors lists errors of nested MultiErrors
+func flattenErrors(err error) []error {
+	multiErr, ok := err.(*MultiError)
+	switch {
+	case err == nil:
+		return nil
+	case !ok:
+		return []error{err}
+	}
+	var res []error
+	for _, e := range multiErr.Errors {
+		res = append(res, flattenErrors(e)...)
+	}
+	return res
+}
+
+// Lint checks every group of config files separately against a new instance of configStruct type
+// and writes reports to w, returns true if all the groups are OK. Files of a group are layers of a single
+// environment, e.g. base and overlay, they are merged in the given order. ConfigPathGetter of params is ignored
+func Lint(params Params, configStruct interface{}, groups [][]string, w io.Writer) (bool, error) {
+	t := reflect.TypeOf(configStruct)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	ok := true
+	for _, paths := range groups {
+		params.ConfigPathGetter = staticPathsGetter(paths)
+		report, err := Check(params, reflect.New(t).Interface())
+		if err != nil {
+			return false, err
+		}
+		if len(report.Files) == 0 {
+			report.Files = paths
+		}
+		if _, err := io.WriteString(w, report.String()); err != nil {
+			return false, err
+		}
+		ok = ok && report.OK()
+	}
+	return ok, nil
+}
+
+type staticPathsGetter []string
+
+func (g staticPathsGetter) GetConfigPath() string {
+	return ""
+}
+
+func (g staticPathsGetter) GetConfigPaths() []string {
+	return g
+}
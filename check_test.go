@@ -0,0 +1,70 @@
+package insconfig_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/soverenio/insconfig"
+)
+
+func Test_Check(t *testing.T) {
+	t.Run("happy", func(t *testing.T) {
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{"testdata/test_config_optional.yaml"},
+		}
+
+		report, err := insconfig.Check(params, &OptionalCfg{})
+		require.NoError(t, err)
+		require.True(t, report.OK())
+		require.Equal(t, []string{"testdata/test_config_optional.yaml"}, report.Files)
+		require.Equal(t, "OK: testdata/test_config_optional.yaml\n", report.String())
+	})
+
+	t.Run("all problems", func(t *testing.T) {
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{"testdata/test_config_errors.yaml"},
+		}
+
+		report, err := insconfig.Check(params, &ErrorsCfg{})
+		require.NoError(t, err)
+		require.False(t, report.OK())
+		require.Len(t, report.Problems, 3)
+		require.IsType(t, &insconfig.UnknownKeysError{}, report.Problems[0])
+		require.IsType(t, &insconfig.DecodeError{}, report.Problems[1])
+		require.IsType(t, &insconfig.MissingKeysError{}, report.Problems[2])
+	})
+
+	t.Run("fail params", func(t *testing.T) {
+		_, err := insconfig.Check(insconfig.Params{EnvPrefix: "testprefix"}, &ErrorsCfg{})
+		require.EqualError(t, err, "ConfigPathGetter should be defined")
+	})
+}
+
+func Test_Lint(t *testing.T) {
+	t.Run("files", func(t *testing.T) {
+		w := &bytes.Buffer{}
+		ok, err := insconfig.Lint(insconfig.Params{EnvPrefix: "testprefix"}, &OptionalCfg{},
+			[][]string{{"testdata/test_config_optional.yaml"}, {"testdata/test_config_typo.yaml"}}, w)
+		require.NoError(t, err)
+		require.False(t, ok)
+		require.Contains(t, w.String(), "OK: testdata/test_config_optional.yaml\n")
+		require.Contains(t, w.String(), "FAIL: testdata/test_config_typo.yaml\n  - ")
+	})
+
+	t.Run("layers", func(t *testing.T) {
+		w := &bytes.Buffer{}
+		ok, err := insconfig.Lint(insconfig.Params{EnvPrefix: "testprefix"}, &CfgStruct{}, [][]string{
+			{"testdata/layers/base.yaml", "testdata/layers/overlay.yaml"},
+			{"testdata/layers/base.yaml", "testdata/layers/overlay_wrong.yaml"},
+		}, w)
+		require.NoError(t, err)
+		require.False(t, ok)
+		require.Contains(t, w.String(), "OK: testdata/layers/base.yaml, testdata/layers/overlay.yaml\n")
+		require.Contains(t, w.String(), "FAIL: testdata/layers/base.yaml, testdata/layers/overlay_wrong.yaml\n  - ")
+		require.Contains(t, w.String(), "nonexistent")
+	})
+}
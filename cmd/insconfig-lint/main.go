@@ -0,0 +1,69 @@
+// insconfig-lint checks config files against a config struct without starting the application,
+// every argument is checked separately and ENV with the prefix is applied to each of them.
+// Comma separated files of an argument are layers merged in the given order, e.g. base and overlay:
+//
+//	go run ./cmd/insconfig-lint --env-prefix example example/example_config.yaml
+//	go run ./cmd/insconfig-lint --env-prefix example config/base.yaml,config/prod.yaml
+//
+// Go can't load a struct at run time, so copy this command next to your config struct and replace Config by it.
+// The command exits with 1 if problems are found and with 2 on wrong arguments
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/soverenio/insconfig"
+)
+
+// Config is the config struct of ./example
+type Config struct {
+	Protocol           string
+	Address            string
+	FixedPublicAddress string
+	HostNetwork        HostNetwork
+	Clients            map[string]Client
+}
+
+type HostNetwork struct {
+	MinTimeout          int
+	MaxTimeout          int
+	TimeoutMult         int
+	SignMessages        bool
+	HandshakeSessionTTL int32
+}
+
+type Client struct {
+	ID      int
+	Address string
+}
+
+func main() {
+	envPrefix := flag.String("env-prefix", "example", "prefix of ENV variables")
+	envSeparator := flag.String("env-separator", "", "separator of config key parts in ENV names")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] config.yaml[,overlay.yaml...]...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	groups := make([][]string, 0, flag.NArg())
+	for _, arg := range flag.Args() {
+		groups = append(groups, strings.Split(arg, ","))
+	}
+	params := insconfig.Params{EnvPrefix: *envPrefix, EnvSeparator: *envSeparator}
+	ok, err := insconfig.Lint(params, &Config{}, groups, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
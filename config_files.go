@@ -0,0 +1,408 @@
+package insconfig
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+const (
+	// includeKey is a reserved config key with a file name or a list of file names to be merged before the file content
+	includeKey = "$include"
+	// maxIncludeDepth limits nesting of included config files
+	maxIncludeDepth = 10
+)
+
+// configFile is a single file merged into the config tree
+type configFile struct {
+	path string
+	// keys contains all the keys defined in file, including intermediate ones: "a", "a.b", "a.b.c",
+	// with positions of their values
+	keys map[string]position
+}
+
+// position of a value in a config file, with position of its key
+type position struct {
+	Line      int
+	Column    int
+	KeyLine   int
+	KeyColumn int
+}
+
+// expandConfigPaths replaces every directory in paths with *.yaml and *.yml files it contains in lexical order,
+// hidden files are skipped
+func expandConfigPaths(paths []string) ([]string, error) {
+	var expanded []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || !info.IsDir() {
+			expanded = append(expanded, path)
+			continue
+		}
+
+		entries, err := ioutil.ReadDir(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read config directory %s", path)
+		}
+		found := false
+		for _, entry := range entries {
+			name := entry.Name()
+			ext := strings.ToLower(filepath.Ext(name))
+			if entry.IsDir() || strings.HasPrefix(name, ".") || (ext != ".yaml" && ext != ".yml") {
+				continue
+			}
+			expanded = append(expanded, filepath.Join(path, name))
+			found = true
+		}
+		if !found {
+			return nil, errors.New(fmt.Sprintf("no config files found in directory %s", path))
+		}
+	}
+	return expanded, nil
+}
+
+// readConfigFiles reads config files in the given order and merges them into a single config tree,
+// all the checks are made later against the merged tree
+func (i *insConfigurator) readConfigFiles(paths []string, configStruct interface{}) error {
+	if len(paths) == 0 && !i.params.FileNotRequired {
+		return errors.New("config path is not defined")
+	}
+
+	i.files = nil
+	for _, configPath := range paths {
+		// paths are expanded one by one, so a wrong directory doesn't drop files of other paths
+		expanded, err := expandConfigPaths([]string{configPath})
+		if err != nil {
+			if !i.params.FileNotRequired {
+				return err
+			}
+			fmt.Printf("failed to load config from '%s'\n", configPath)
+			continue
+		}
+		for _, path := range expanded {
+			if _, err := os.Stat(path); err != nil && i.params.FileNotRequired {
+				fmt.Printf("failed to load config from '%s'\n", path)
+				continue
+			}
+			if err := i.readConfigFile(path, configStruct, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readConfigFile reads file and merges it into the config tree. Files listed in the includeKey are merged
+// before the file content, so the file may override included values. includedFrom is the chain of including files
+func (i *insConfigurator) readConfigFile(path string, configStruct interface{}, includedFrom []string) error {
+	if len(includedFrom) > maxIncludeDepth {
+		return errors.New(fmt.Sprintf("config files include depth exceeds %d: %s", maxIncludeDepth, strings.Join(append(includedFrom, path), " -> ")))
+	}
+	for _, p := range includedFrom {
+		if samePath(p, path) {
+			return errors.New(fmt.Sprintf("config files include cycle: %s", strings.Join(append(includedFrom, path), " -> ")))
+		}
+	}
+
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read config file")
+	}
+	content := make(map[string]interface{})
+	if err := yaml.Unmarshal(bytes, &content); err != nil {
+		return errors.Wrapf(err, "failed to parse config file %s", path)
+	}
+	// this check is necessary for duplicated map keys in YAML, parser silently overrides them
+	if err := checkDuplicatedKeys(path, bytes, configStruct); i.errs.add(err) {
+		return err
+	}
+
+	includes, err := includedPaths(path, content[includeKey])
+	if err != nil {
+		return err
+	}
+	delete(content, includeKey)
+	for _, included := range includes {
+		if err := i.readConfigFile(included, configStruct, append(includedFrom, path)); err != nil {
+			return err
+		}
+	}
+
+	keys := make(map[string]position)
+	collectKeys(content, "", keys)
+	collectPositions(bytes, keys)
+	if err := i.viper.MergeConfigMap(content); err != nil {
+		return errors.Wrapf(err, "failed to merge config file %s", path)
+	}
+	i.files = append(i.files, configFile{path: path, keys: keys})
+	return nil
+}
+
+// includedPaths returns files from the includeKey value resolved relative to the including file
+func includedPaths(path string, value interface{}) ([]string, error) {
+	var names []string
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		names = []string{v}
+	case []interface{}:
+		for _, name := range v {
+			s, ok := name.(string)
+			if !ok {
+				return nil, errors.New(fmt.Sprintf("%s in config file %s must contain file names, got: %v", includeKey, path, name))
+			}
+			names = append(names, s)
+		}
+	default:
+		return nil, errors.New(fmt.Sprintf("%s in config file %s must be a file name or a list of file names, got: %v", includeKey, path, value))
+	}
+
+	for n, name := range names {
+		if !filepath.IsAbs(name) {
+			names[n] = filepath.Join(filepath.Dir(path), name)
+		}
+	}
+	return expandConfigPaths(names)
+}
+
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return absA == absB
+}
+
+func checkDuplicatedKeys(path string, bytes []byte, configStruct interface{}) error {
+	// Unmarshal into a fresh value, configStruct is filled by viper only
+	err := yaml.UnmarshalStrict(bytes, reflect.New(reflect.Indirect(reflect.ValueOf(configStruct)).Type()).Interface())
+	if err == nil || !strings.Contains(err.Error(), "already set in map") {
+		return nil
+	}
+	dupErr := &DuplicateKeyError{File: path, err: err}
+	var root yaml3.Node
+	if yaml3.Unmarshal(bytes, &root) == nil && len(root.Content) > 0 {
+		walkDuplicates(root.Content[0], "", func(key string, keyNode *yaml3.Node) {
+			dupErr.Keys = append(dupErr.Keys, key)
+			dupErr.Sources = append(dupErr.Sources, Source{Kind: SourceFile, Name: path, Line: keyNode.Line, Column: keyNode.Column})
+		})
+	}
+	return dupErr
+}
+
+// walkDuplicates calls fn for every repeated key of every mapping with the position of the repeated key
+func walkDuplicates(node *yaml3.Node, prefix string, fn func(key string, keyNode *yaml3.Node)) {
+	if node.Kind != yaml3.MappingNode {
+		return
+	}
+	seen := make(map[string]bool)
+	for n := 0; n+1 < len(node.Content); n += 2 {
+		keyNode, valueNode := node.Content[n], node.Content[n+1]
+		key := keyNode.Value
+		if prefix != "" {
+			key = strings.Join([]string{prefix, key}, ".")
+		}
+		if seen[keyNode.Value] {
+			fn(strings.ToLower(key), keyNode)
+		}
+		seen[keyNode.Value] = true
+		walkDuplicates(valueNode, key, fn)
+	}
+}
+
+func collectKeys(content map[string]interface{}, prefix string, keys map[string]position) {
+	for k, v := range content {
+		key := strings.ToLower(k)
+		if prefix != "" {
+			key = strings.Join([]string{prefix, key}, ".")
+		}
+		keys[key] = position{}
+		collectNestedKeys(v, key, keys)
+	}
+}
+
+// collectNestedKeys collects keys of maps and sequence elements, elements are keyed by index, e.g. "items.0.id"
+func collectNestedKeys(value interface{}, key string, keys map[string]position) {
+	switch nested := value.(type) {
+	case map[string]interface{}:
+		collectKeys(nested, key, keys)
+	case map[interface{}]interface{}:
+		collectKeys(toStringMap(nested), key, keys)
+	case []interface{}:
+		for n, element := range nested {
+			elementKey := strings.Join([]string{key, strconv.Itoa(n)}, ".")
+			keys[elementKey] = position{}
+			collectNestedKeys(element, elementKey, keys)
+		}
+	}
+}
+
+// collectPositions fills positions of the collected keys, yaml.v2 doesn't provide them
+func collectPositions(bytes []byte, keys map[string]position) {
+	var root yaml3.Node
+	if err := yaml3.Unmarshal(bytes, &root); err != nil || len(root.Content) == 0 {
+		return
+	}
+	walkPositions(root.Content[0], "", keys)
+}
+
+func walkPositions(node *yaml3.Node, prefix string, keys map[string]position) {
+	if node.Kind == yaml3.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind == yaml3.SequenceNode {
+		// elements have no key nodes, positions of values are used
+		for n, valueNode := range node.Content {
+			key := strings.Join([]string{prefix, strconv.Itoa(n)}, ".")
+			if _, ok := keys[key]; ok {
+				keys[key] = position{Line: valueNode.Line, Column: valueNode.Column, KeyLine: valueNode.Line, KeyColumn: valueNode.Column}
+			}
+			walkPositions(valueNode, key, keys)
+		}
+		return
+	}
+	if node.Kind != yaml3.MappingNode {
+		return
+	}
+	for n := 0; n+1 < len(node.Content); n += 2 {
+		keyNode, valueNode := node.Content[n], node.Content[n+1]
+		key := strings.ToLower(keyNode.Value)
+		if prefix != "" {
+			key = strings.Join([]string{prefix, key}, ".")
+		}
+		if _, ok := keys[key]; ok {
+			keys[key] = position{Line: valueNode.Line, Column: valueNode.Column, KeyLine: keyNode.Line, KeyColumn: keyNode.Column}
+		}
+		walkPositions(valueNode, key, keys)
+	}
+}
+
+// lastFileWithKey returns the last merged file where key is defined, its value is the effective one
+func (i *insConfigurator) lastFileWithKey(key string) (configFile, position, bool) {
+	for n := len(i.files) - 1; n >= 0; n-- {
+		if pos, ok := i.files[n].keys[strings.ToLower(key)]; ok {
+			return i.files[n], pos, true
+		}
+	}
+	return configFile{}, position{}, false
+}
+
+func toStringMap(m map[interface{}]interface{}) map[string]interface{} {
+	res := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		res[fmt.Sprint(k)] = v
+	}
+	return res
+}
+
+// fileSources returns the places where key is defined in all the merged files, positions of keys are used
+func (i *insConfigurator) fileSources(key string) []Source {
+	var sources []Source
+	for _, f := range i.files {
+		if pos, ok := f.keys[strings.ToLower(key)]; ok {
+			sources = append(sources, Source{Kind: SourceFile, Name: f.path, Line: pos.KeyLine, Column: pos.KeyColumn})
+		}
+	}
+	return sources
+}
+
+// valueSource returns the place where the effective value of key is set
+func (i *insConfigurator) valueSource(key string) (Source, bool) {
+	envName := i.envName(key)
+	if _, ok := os.LookupEnv(envName); ok {
+		return Source{Kind: SourceEnv, Name: envName}, true
+	}
+	if file, pos, ok := i.lastFileWithKey(key); ok {
+		return Source{Kind: SourceFile, Name: file.path, Line: pos.Line, Column: pos.Column}, true
+	}
+	return Source{}, false
+}
+
+// filesWithKey returns paths of the files where key is defined
+func (i *insConfigurator) filesWithKey(key string) []string {
+	var paths []string
+	for _, f := range i.files {
+		if _, ok := f.keys[strings.ToLower(key)]; ok {
+			paths = append(paths, f.path)
+		}
+	}
+	return paths
+}
+
+// describeMissingKey adds the files that define an incomplete part of the key, if there are several config files
+func (i *insConfigurator) describeMissingKey(key string) string {
+	if len(i.files) < 2 {
+		return key
+	}
+	parts := strings.Split(key, ".")
+	for n := len(parts) - 1; n > 0; n-- {
+		prefix := strings.Join(parts[:n], ".")
+		if paths := i.filesWithKey(prefix); len(paths) > 0 {
+			return fmt.Sprintf("%s (%s is defined in %s)", key, prefix, strings.Join(paths, ", "))
+		}
+	}
+	return key
+}
+
+// decodeError converts mapstructure error into UnknownKeysError and DecodeError for every failed key
+func (i *insConfigurator) decodeError(err error, configStruct interface{}) error {
+	var mapstructureErr *mapstructure.Error
+	if !errors.As(err, &mapstructureErr) {
+		return &DecodeError{err: err}
+	}
+
+	var (
+		errs    []error
+		unknown = &UnknownKeysError{Sources: make(map[string][]Source), Suggestions: make(map[string]string)}
+		// keys of a fresh value contain map placeholders, configStruct may be partially decoded.
+		// errors of deepFieldNames are reported by Load
+		configType    = reflect.Indirect(reflect.ValueOf(configStruct)).Type()
+		structKeys, _ = deepFieldNames(reflect.New(configType).Interface(), "")
+	)
+	for _, msg := range mapstructureErr.Errors {
+		if match := invalidKeysRegexp.FindStringSubmatch(msg); match != nil {
+			for _, key := range strings.Split(match[2], ", ") {
+				fullKey := strings.ToLower(key)
+				if match[1] != "" {
+					fullKey = strings.Join([]string{mapstructureNameToKey(configType, match[1]), fullKey}, ".")
+				}
+				unknown.Keys = append(unknown.Keys, fullKey)
+				if sources := i.fileSources(fullKey); len(sources) > 0 {
+					unknown.Sources[fullKey] = sources
+				}
+				if suggestion := suggestKey(fullKey, structKeys); suggestion != "" {
+					unknown.Suggestions[fullKey] = suggestion
+				}
+			}
+			continue
+		}
+
+		decodeErr := &DecodeError{err: errors.New(msg)}
+		if match := fieldNameRegexp.FindStringSubmatch(msg); match != nil {
+			decodeErr.Key = mapstructureNameToKey(configType, match[1])
+			decodeErr.Source, _ = i.valueSource(decodeErr.Key)
+		}
+		errs = append(errs, decodeErr)
+	}
+	if len(unknown.Keys) > 0 {
+		errs = append([]error{unknown}, errs...)
+	}
+	return combineErrors(errs)
+}
+
+var (
+	invalidKeysRegexp = regexp.MustCompile(`^'(.*)' has invalid keys: (.+)$`)
+	fieldNameRegexp   = regexp.MustCompile(`'([^']*)'`)
+)
@@ -6,21 +6,72 @@ import (
 	flag "github.com/spf13/pflag"
 )
 
-// DefaultPathGetter adds "--config" flag and read path from it
+// GenConfigPathGetter - implement this in addition to ConfigPathGetter to make Load generate a config template
+// instead of loading config. ok is true if the template is requested, an empty path means stdout
+type GenConfigPathGetter interface {
+	GetGenConfigPath() (path string, ok bool)
+}
+
+// addGenConfigFlag adds "--gen-config[=path]" flag, the flag without value is "-" meaning stdout
+func addGenConfigFlag() *string {
+	genConfig := flag.String("gen-config", "", "write config template to the path or to stdout and exit")
+	flag.Lookup("gen-config").NoOptDefVal = "-"
+	return genConfig
+}
+
+func genConfigPath(genConfig *string) (string, bool) {
+	switch {
+	case genConfig == nil || *genConfig == "":
+		return "", false
+	case *genConfig == "-":
+		return "", true
+	}
+	return *genConfig, true
+}
+
+// DumpConfigGetter - implement this in addition to ConfigPathGetter to make Load print the loaded config
+// with masked secrets to Params.Output, see ErrConfigDumped
+type DumpConfigGetter interface {
+	GetDumpConfig() bool
+}
+
+// addDumpConfigFlag adds "--dump-config" flag
+func addDumpConfigFlag() *bool {
+	return flag.Bool("dump-config", false, "print the loaded config with masked secrets and exit")
+}
+
+// DefaultPathGetter adds "--config", "--gen-config" and "--dump-config" flags and read paths from them
 type DefaultPathGetter struct {
 	GoFlags *goflag.FlagSet
+
+	genConfig  *string
+	dumpConfig *bool
 }
 
 func (g *DefaultPathGetter) GetConfigPath() string {
 	configPath := flag.String("config", "", "path to config")
+	g.genConfig = addGenConfigFlag()
+	g.dumpConfig = addDumpConfigFlag()
 	flag.Parse()
 	return *configPath
 }
 
+func (g *DefaultPathGetter) GetGenConfigPath() (string, bool) {
+	return genConfigPath(g.genConfig)
+}
+
+func (g *DefaultPathGetter) GetDumpConfig() bool {
+	return g.dumpConfig != nil && *g.dumpConfig
+}
+
 // FlagPathGetter made for go flags compatibility
-// Adds "--config" flag and read path from it, custom go flags should be created before and set to GoFlags
+// Adds "--config", "--gen-config" and "--dump-config" flags and read paths from them,
+// custom go flags should be created before and set to GoFlags
 type FlagPathGetter struct {
 	GoFlags *goflag.FlagSet
+
+	genConfig  *string
+	dumpConfig *bool
 }
 
 func (g *FlagPathGetter) GetConfigPath() string {
@@ -28,14 +79,28 @@ func (g *FlagPathGetter) GetConfigPath() string {
 		flag.CommandLine.AddGoFlagSet(g.GoFlags)
 	}
 	configPath := flag.String("config", "", "path to config")
+	g.genConfig = addGenConfigFlag()
+	g.dumpConfig = addDumpConfigFlag()
 	flag.Parse()
 	return *configPath
 }
 
+func (g *FlagPathGetter) GetGenConfigPath() (string, bool) {
+	return genConfigPath(g.genConfig)
+}
+
+func (g *FlagPathGetter) GetDumpConfig() bool {
+	return g.dumpConfig != nil && *g.dumpConfig
+}
+
 // PFlagPathGetter made for spf13/pflags compatibility.
-// Adds "--config" flag and read path from it, custom pflags should be created before and set to PFlags
+// Adds "--config", "--gen-config" and "--dump-config" flags and read paths from them,
+// custom pflags should be created before and set to PFlags
 type PFlagPathGetter struct {
 	PFlags *flag.FlagSet
+
+	genConfig  *string
+	dumpConfig *bool
 }
 
 func (g *PFlagPathGetter) GetConfigPath() string {
@@ -43,6 +108,16 @@ func (g *PFlagPathGetter) GetConfigPath() string {
 		flag.CommandLine.AddFlagSet(g.PFlags)
 	}
 	configPath := flag.String("config", "", "path to config")
+	g.genConfig = addGenConfigFlag()
+	g.dumpConfig = addDumpConfigFlag()
 	flag.Parse()
 	return *configPath
 }
+
+func (g *PFlagPathGetter) GetGenConfigPath() (string, bool) {
+	return genConfigPath(g.genConfig)
+}
+
+func (g *PFlagPathGetter) GetDumpConfig() bool {
+	return g.dumpConfig != nil && *g.dumpConfig
+}
@@ -3,10 +3,11 @@ package insconfig
 import (
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
 	"reflect"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/mitchellh/mapstructure"
@@ -27,6 +28,13 @@ type Params struct {
 	ConfigPathGetter ConfigPathGetter
 	// FileNotRequired - do not return error on file not found
 	FileNotRequired bool
+	// AllErrors - do not stop on the first failed check, return all the problems found in MultiError
+	AllErrors bool
+	// EnvSeparator separates parts of config keys in ENV names, "_" by default.
+	// Set it to e.g. "__" if key names contain "_": EXAMPLE_HOST__MAX_CONN is host.max_conn
+	EnvSeparator string
+	// Output is a writer of the dumped config and of the config template requested without path, os.Stdout by default
+	Output io.Writer
 }
 
 // ConfigPathGetter - implement this if you don't want to use config path from --config flag
@@ -34,23 +42,44 @@ type ConfigPathGetter interface {
 	GetConfigPath() string
 }
 
+// MultiPathGetter - implement this in addition to ConfigPathGetter if you want to load several config files.
+// Files are merged in the given order, values from the latter files override values from the former ones
+type MultiPathGetter interface {
+	GetConfigPaths() []string
+}
+
 type insConfigurator struct {
-	params     Params
-	viper      *viper.Viper
-	configPath string
+	params      Params
+	viper       *viper.Viper
+	configPaths []string
+	files       []configFile
+	sources     map[string]Source
+	errs        *errorCollector
 }
 
 // New creates new insConfigurator with params
 func New(params Params) insConfigurator {
 	return insConfigurator{
-		params:     params,
-		configPath: params.ConfigPathGetter.GetConfigPath(),
-		viper:      viper.New(),
+		params:      params,
+		configPaths: getConfigPaths(params.ConfigPathGetter),
+		viper:       viper.New(),
+	}
+}
+
+func getConfigPaths(getter ConfigPathGetter) []string {
+	if multiGetter, ok := getter.(MultiPathGetter); ok {
+		return multiGetter.GetConfigPaths()
 	}
+	return []string{getter.GetConfigPath()}
 }
 
 // Load loads configuration from path, env and makes checks
 // configStruct is a pointer to your config
+// If a config template is requested by GenConfigPathGetter (e.g. by "--gen-config" flag), Load writes the template
+// with values of configStruct instead and returns ErrConfigGenerated, the template requested without path
+// is written to Params.Output
+// If the loaded config is requested by DumpConfigGetter (e.g. by "--dump-config" flag), Load prints it with masked
+// secrets and sources of values to Params.Output and returns ErrConfigDumped
 func (i *insConfigurator) Load(configStruct interface{}) error {
 	if i.params.EnvPrefix == "" {
 		return errors.New("EnvPrefix should be defined")
@@ -58,52 +87,85 @@ func (i *insConfigurator) Load(configStruct interface{}) error {
 	if i.params.ConfigPathGetter == nil {
 		return errors.New("ConfigPathGetter should be defined")
 	}
+	if getter, ok := i.params.ConfigPathGetter.(GenConfigPathGetter); ok {
+		if path, ok := getter.GetGenConfigPath(); ok {
+			if err := genConfig(path, i.output(), configStruct); err != nil {
+				return err
+			}
+			return ErrConfigGenerated
+		}
+	}
+
+	if err := i.load(i.configPaths, configStruct); err != nil {
+		return err
+	}
+	if getter, ok := i.params.ConfigPathGetter.(DumpConfigGetter); ok && getter.GetDumpConfig() {
+		if err := NewYamlDumperWithSources(configStruct, i.Provenance()).DumpTo(i.output()); err != nil {
+			return err
+		}
+		return ErrConfigDumped
+	}
+	return nil
+}
 
-	return i.load(i.configPath, configStruct)
+func (i *insConfigurator) output() io.Writer {
+	if i.params.Output == nil {
+		return os.Stdout
+	}
+	return i.params.Output
 }
 
-func (i *insConfigurator) load(path string, configStruct interface{}) error {
+// genConfig writes config template to the file or to out if path is empty
+func genConfig(path string, out io.Writer, configStruct interface{}) error {
+	if path == "" {
+		return NewYamlTemplaterStruct(configStruct).TemplateTo(out)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to create config template file")
+	}
+	if err := NewYamlTemplaterStruct(configStruct).TemplateTo(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
 
+func (i *insConfigurator) load(paths []string, configStruct interface{}) error {
+	// config tree is read from scratch, so keys removed from files since the previous Load are not kept
+	i.viper = viper.New()
 	i.viper.AutomaticEnv()
-	i.viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	i.viper.SetEnvKeyReplacer(strings.NewReplacer(".", i.envSeparator()))
 	i.viper.SetEnvPrefix(i.params.EnvPrefix)
+	i.sources = make(map[string]Source)
+	i.errs = &errorCollector{all: i.params.AllErrors}
 
-	i.viper.SetConfigFile(path)
-	if err := i.viper.ReadInConfig(); err != nil {
-		if !i.params.FileNotRequired {
-			return err
-		}
-		fmt.Printf("failed to load config from '%s'\n", path)
-	}
-
-	// this 'if' block necessary for check duplicated map keys in YAML
-	if !i.params.FileNotRequired {
-		bytes, err := ioutil.ReadFile(path)
-		if err != nil {
-			return errors.Wrapf(err, "failed to read config file")
-		}
-		err = yaml.UnmarshalStrict(bytes, configStruct)
-		if err != nil && strings.Contains(err.Error(), "already set in map") {
-			return errors.Wrapf(err, "failed to unmarshal config file into configuration structure")
-		}
+	if err := i.readConfigFiles(paths, configStruct); err != nil {
+		return err
 	}
 
-	i.params.ViperHooks = append(i.params.ViperHooks, mapstructure.StringToTimeDurationHookFunc(), mapstructure.StringToSliceHookFunc(","))
-	err := i.viper.UnmarshalExact(configStruct, viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
-		i.params.ViperHooks...,
+	// params are not modified, so Load may be called several times
+	hooks := append(append([]mapstructure.DecodeHookFunc{namingHook()}, i.params.ViperHooks...),
+		mapstructure.StringToTimeDurationHookFunc(), mapstructure.StringToSliceHookFunc(","), mapstructure.TextUnmarshallerHookFunc())
+	decodeErr := i.viper.UnmarshalExact(configStruct, viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+		hooks...,
 	)))
-	if err != nil {
-		return errors.Wrapf(err, "failed to unmarshal config file into configuration structure")
+	if decodeErr != nil && i.errs.add(i.decodeError(decodeErr, configStruct)) {
+		return i.errs.result()
 	}
-	configStructKeys, err := deepFieldNames(configStruct, "", false)
+	configStructKeys, err := deepFieldNames(configStruct, "")
 	if err != nil {
 		return err
 	}
 
 	configStructKeys, mapKeys := separateKeys(configStructKeys)
-	configStructKeys, err = i.checkNoExtraENVValues(configStructKeys, mapKeys)
-	if err != nil {
-		return err
+	if i.errs.add(i.checkEnvNames(configStruct)) {
+		return i.errs.result()
+	}
+	slices := sliceKeys(reflect.TypeOf(configStruct), configStructKeys)
+	configStructKeys, err = i.checkNoExtraENVValues(configStructKeys, mapKeys, slices)
+	if i.errs.add(err) {
+		return i.errs.result()
 	}
 
 	for k := range mapKeys {
@@ -112,46 +174,96 @@ func (i *insConfigurator) load(path string, configStruct interface{}) error {
 		}
 	}
 
-	err = i.checkAllValuesIsSet(configStructKeys)
-	if err != nil {
-		return err
+	if i.errs.add(i.checkAllValuesIsSet(configStructKeys, tagRules(configStruct))) {
+		return i.errs.result()
 	}
 
 	// Second Unmarshal needed because of bug https://github.com/spf13/viper/issues/761
 	// This should be evaluated after manual values overriding is done
 	err = i.viper.UnmarshalExact(configStruct, viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
-		i.params.ViperHooks...,
+		hooks...,
 	)))
-	if err != nil {
-		return errors.Wrapf(err, "failed to unmarshal config file into configuration structure 2")
+	// errors of the first Unmarshal are already reported
+	if err != nil && decodeErr == nil && i.errs.add(i.decodeError(err, configStruct)) {
+		return i.errs.result()
 	}
-	return nil
+	if len(i.errs.errs) > 0 {
+		// values of a broken config are not validated
+		return i.errs.result()
+	}
+
+	i.collectSources(configStructKeys)
+
+	return validateValues(configStruct)
 }
 
-func (i *insConfigurator) checkNoExtraENVValues(structKeys []string, mapKeys map[string]bool) ([]string, error) {
-	var errorKeys []string
+func (i *insConfigurator) checkNoExtraENVValues(structKeys []string, mapKeys map[string]bool, slices map[string]reflect.Type) ([]string, error) {
+	var errorKeys, errorVars []string
+	sliceValues := make(map[string][]sliceEnvValue)
+	envKeys := make(map[string]string, len(structKeys))
+	for _, key := range structKeys {
+		envKeys[i.envName(key)] = key
+	}
 	prefixLen := len(i.params.EnvPrefix)
 	for _, e := range os.Environ() {
 		if len(e) > prefixLen && e[0:prefixLen]+"_" == strings.ToUpper(i.params.EnvPrefix)+"_" {
 			kv := strings.SplitN(e, "=", 2)
-			key := strings.ReplaceAll(strings.Replace(strings.ToLower(kv[0]), i.params.EnvPrefix+"_", "", 1), "_", ".")
+			name := strings.ToUpper(kv[0])
 
-			if k, pref, match := matchMapKey(mapKeys, key); match && !stringInSlice(key, structKeys) {
-				structKeys = append(structKeys, newKeys(mapKeys, k, pref)...)
+			key, ok := envKeys[name]
+			if pattern, mapKeysValues, match := i.matchMapEnvName(mapKeys, name); match && !ok {
+				for _, newKey := range newKeys(mapKeys, pattern, mapKeysValues) {
+					structKeys = append(structKeys, newKey)
+					envKeys[i.envName(newKey)] = newKey
+				}
+				key, ok = envKeys[name]
+			}
+			if sliceKey, value, match := i.matchSliceEnvName(slices, name, kv[1]); match && !ok {
+				sliceValues[sliceKey] = append(sliceValues[sliceKey], value)
+				continue
 			}
 
-			if stringInSlice(key, structKeys) {
+			if ok {
 				// This manually sets value from ENV and overrides everything, this temporarily fix issue https://github.com/spf13/viper/issues/761
 				i.viper.Set(key, kv[1])
+				i.sources[key] = Source{Kind: SourceEnv, Name: kv[0]}
 			} else {
-				errorKeys = append(errorKeys, key)
+				errorKeys = append(errorKeys, strings.ReplaceAll(strings.Replace(strings.ToLower(kv[0]), i.params.EnvPrefix+"_", "", 1), strings.ToLower(i.envSeparator()), "."))
+				errorVars = append(errorVars, kv[0])
 			}
 		}
 	}
+	var missingKeys []string
+	for sliceKey, values := range sliceValues {
+		invalid, missing := i.setSliceEnvValues(sliceKey, slices[sliceKey], values)
+		for _, v := range invalid {
+			errorKeys = append(errorKeys, v.key(sliceKey))
+			errorVars = append(errorVars, v.name)
+		}
+		missingKeys = append(missingKeys, missing...)
+	}
+	errs := &errorCollector{all: true}
 	if len(errorKeys) > 0 {
-		return structKeys, errors.New(fmt.Sprintf("Wrong config keys found in ENV: %s", strings.Join(errorKeys, ", ")))
+		sort.Strings(errorKeys)
+		sort.Strings(errorVars)
+		candidates := append([]string{}, structKeys...)
+		for k := range mapKeys {
+			candidates = append(candidates, k)
+		}
+		suggestions := make(map[string]string)
+		for _, key := range errorKeys {
+			if suggestion := suggestKey(key, candidates); suggestion != "" {
+				suggestions[key] = suggestion
+			}
+		}
+		errs.add(&UnknownEnvKeysError{Keys: errorKeys, Vars: errorVars, Suggestions: suggestions})
+	}
+	if len(missingKeys) > 0 {
+		// fields of new slice elements
+		sort.Strings(missingKeys)
+		errs.add(&MissingKeysError{Keys: missingKeys})
 	}
-	return structKeys, nil
+	return structKeys, errs.result()
 }
 
 func separateKeys(list []string) ([]string, map[string]bool) {
@@ -167,50 +279,173 @@ func separateKeys(list []string) ([]string, map[string]bool) {
 	return structKeys, mapKeys
 }
 
-func newKeys(keys map[string]bool, key, pref string) []string {
+// newKeys fills placeholders of pattern with map keys and returns keys of the new map entry: the key matching pattern
+// and all the sibling keys. Placeholder keys sharing the entry are marked as used, their placeholders nested deeper
+// than the pattern ones are kept and such partial keys are added to keys, so nested maps of the entry are checked too
+func newKeys(keys map[string]bool, pattern string, mapKeys []string) []string {
 	var names []string
-	oldStr := strings.Join([]string{pref, placeholder}, "")
-	newStr := strings.Join([]string{pref, key}, "")
+	patternParts := strings.Split(pattern, ".")
 	for k := range keys {
-		if strings.HasPrefix(k, oldStr) {
-			names = append(names, strings.Replace(k, oldStr, newStr, 1))
-			keys[k] = true
+		parts := strings.Split(k, ".")
+		filled := 0
+		for n := 0; n < len(parts) && n < len(patternParts) && parts[n] == patternParts[n]; n++ {
+			if parts[n] == placeholder {
+				parts[n] = mapKeys[filled]
+				filled++
+			}
+		}
+		if filled == 0 {
+			continue
+		}
+		keys[k] = true
+
+		newKey := strings.Join(parts, ".")
+		if !strings.Contains(newKey, placeholder) {
+			names = append(names, newKey)
+		} else if _, ok := keys[newKey]; !ok {
+			keys[newKey] = false
 		}
 	}
 	return names
 }
 
-func matchMapKey(keys map[string]bool, key string) (string, string, bool) {
-	for k := range keys {
-		l := strings.ToLower(k)
-		pattern := strings.ReplaceAll(l, ".", "\\.")
-		pattern = strings.ReplaceAll(pattern, placeholder, ".+")
-		match, err := regexp.MatchString(pattern, key)
-		if err != nil {
-			fmt.Println(err)
+// envName returns the name of ENV variable overriding the key
+func (i *insConfigurator) envName(key string) string {
+	return strings.ToUpper(i.params.EnvPrefix + "_" + strings.ReplaceAll(key, ".", i.envSeparator()))
+}
+
+func (i *insConfigurator) envSeparator() string {
+	if i.params.EnvSeparator == "" {
+		return "_"
+	}
+	return i.params.EnvSeparator
+}
+
+// checkEnvNames checks that every ENV name addresses a single key of the config structure.
+// Keys of a fresh value are checked, so entries of maps from config files don't make names ambiguous
+func (i *insConfigurator) checkEnvNames(configStruct interface{}) error {
+	configType := reflect.Indirect(reflect.ValueOf(configStruct)).Type()
+	structKeys, err := deepFieldNames(reflect.New(configType).Interface(), "")
+	if err != nil {
+		return err
+	}
+	keys := make(map[string][]string)
+	for _, key := range structKeys {
+		name := i.envName(key)
+		keys[name] = append(keys[name], key)
+	}
+
+	var names []string
+	for name, nameKeys := range keys {
+		if len(nameKeys) > 1 {
+			sort.Strings(nameKeys)
+			names = append(names, name)
+		} else {
+			delete(keys, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+	return &AmbiguousEnvKeysError{Vars: names, Keys: keys}
+}
+
+// envPlaceholderRegexp matches a map key in ENV name, with the default separator keys of outer maps can't contain "_"
+const envPlaceholderRegexp = "([^_]+)"
+
+// matchMapEnvName finds the most specific placeholder key matching ENV variable name,
+// returns the placeholder key and the map keys of its placeholders
+func (i *insConfigurator) matchMapEnvName(mapKeys map[string]bool, name string) (string, []string, bool) {
+	patterns := make([]string, 0, len(mapKeys))
+	for k := range mapKeys {
+		patterns = append(patterns, k)
+	}
+	// keys with less placeholders are more specific
+	sort.Slice(patterns, func(a, b int) bool {
+		countA, countB := strings.Count(patterns[a], placeholder), strings.Count(patterns[b], placeholder)
+		if countA != countB {
+			return countA < countB
+		}
+		return patterns[a] < patterns[b]
+	})
+
+	for _, pattern := range patterns {
+		parts := strings.Split(pattern, ".")
+		last := 0
+		for n, part := range parts {
+			if part == placeholder {
+				last = n
+			}
+		}
+		separator := regexp.QuoteMeta(strings.ToUpper(i.envSeparator()))
+		re := "^" + regexp.QuoteMeta(strings.ToUpper(i.params.EnvPrefix)) + "_"
+		for n, part := range parts {
+			if n > 0 {
+				re += separator
+			}
+			switch {
+			case part != placeholder:
+				re += regexp.QuoteMeta(strings.ToUpper(part))
+			case n == last:
+				// the last map key may contain the separator
+				re += "(.+)"
+			case i.envSeparator() == "_":
+				re += envPlaceholderRegexp
+			default:
+				re += "(.+?)"
+			}
 		}
-		if match {
-			parts := strings.Split(l, placeholder)
-			return strings.TrimSuffix(strings.TrimPrefix(key, parts[0]), parts[1]), parts[0], true
+		match := regexp.MustCompile(re + "$").FindStringSubmatch(name)
+		if match == nil {
+			continue
 		}
+		values := make([]string, 0, len(match)-1)
+		for _, value := range match[1:] {
+			values = append(values, strings.ToLower(value))
+		}
+		return pattern, values, true
 	}
-	return "", "", false
+	return "", nil, false
 }
 
-func (i *insConfigurator) checkAllValuesIsSet(structKeys []string) error {
-	var errorKeys []string
+// checkAllValuesIsSet sets defaults of the keys without values, keys without values which are neither optional nor have defaults are reported
+func (i *insConfigurator) checkAllValuesIsSet(structKeys []string, rules missingKeysRules) error {
+	var errorKeys, described []string
 	allKeys := i.viper.AllKeys()
 	for _, keyName := range structKeys {
 		if !i.viper.IsSet(keyName) {
 			// Due to a bug https://github.com/spf13/viper/issues/447 we can't use InConfig, so
 			if !stringInSlice(keyName, allKeys) {
+				if value, hasDefault, ok := rules.satisfied(keyName, allKeys); ok {
+					// placeholder keys of empty maps are satisfied by defaults but have no values
+					if hasDefault && !strings.Contains(keyName, placeholder) {
+						i.viper.SetDefault(keyName, value)
+						i.sources[keyName] = Source{Kind: SourceDefault}
+					}
+					continue
+				}
 				errorKeys = append(errorKeys, keyName)
+				described = append(described, i.describeMissingKey(keyName))
 			}
 			// Value of this key is "null" but it's set in config file
 		}
 	}
 	if len(errorKeys) > 0 {
-		return errors.New(fmt.Sprintf("Keys is not defined in config: %s", strings.Join(errorKeys, ", ")))
+		// keys unknown to config structure may be misspelled missing keys
+		var unknownKeys []string
+		for _, key := range allKeys {
+			if !stringInSlice(key, structKeys) {
+				unknownKeys = append(unknownKeys, key)
+			}
+		}
+		suggestions := make(map[string]string)
+		for _, key := range errorKeys {
+			if suggestion := suggestKey(key, unknownKeys); suggestion != "" {
+				suggestions[key] = suggestion
+			}
+		}
+		return &MissingKeysError{Keys: errorKeys, Suggestions: suggestions, described: described}
 	}
 	return nil
 }
@@ -224,56 +459,38 @@ func stringInSlice(a string, list []string) bool {
 	return false
 }
 
-func deepFieldNames(iface interface{}, prefix string, inMap bool) ([]string, error) {
+func deepFieldNames(iface interface{}, prefix string) ([]string, error) {
 	names := make([]string, 0)
 	ifv := reflect.Indirect(reflect.ValueOf(iface))
+	if v := reflect.ValueOf(iface); v.Kind() == reflect.Ptr && v.IsNil() && !isEnvLeaf(v.Type().Elem()) {
+		// fields of a nil struct are config keys too, e.g. of an absent optional block
+		ifv = reflect.Zero(v.Type().Elem())
+	}
 
 	switch ifv.Kind() {
 	case reflect.Struct:
 		for i := 0; i < ifv.Type().NumField(); i++ {
 			v := ifv.Field(i)
-			tagValue := ifv.Type().Field(i).Tag.Get("mapstructure")
-			tagParts := strings.Split(tagValue, ",")
-
-			// If "squash" is specified in the tag, we squash the field down.
-			squash := false
-			for _, tag := range tagParts[1:] {
-				if tag == "squash" {
-					squash = true
-					break
-				}
-			}
 
-			newPrefix := ""
-			currPrefix := ""
-			if !squash {
-				currPrefix = ifv.Type().Field(i).Name
-			}
-			if prefix != "" {
-				newPrefix = strings.Join([]string{prefix, currPrefix}, ".")
-			} else {
-				newPrefix = currPrefix
+			newPrefix, ok := fieldKey(prefix, ifv.Type().Field(i))
+			if !ok {
+				continue
 			}
 
-			fieldNames, err := deepFieldNames(v.Interface(), strings.ToLower(newPrefix), inMap)
+			fieldNames, err := deepFieldNames(v.Interface(), newPrefix)
 			if err != nil {
 				return nil, err
 			}
 			names = append(names, fieldNames...)
 		}
 	case reflect.Map:
-		if inMap {
-			return nil, errors.New("nested maps are not allowed in config")
-		}
-		inMap = true
-		keyKind := ifv.Type().Key().Kind()
-		if keyKind != reflect.String {
-			return nil, errors.New(fmt.Sprintf("maps in config must have string keys but got: %s key in %s", keyKind, ifv.Type()))
+		if keyType := ifv.Type().Key(); !isSupportedMapKey(keyType) {
+			return nil, errors.New(fmt.Sprintf("maps in config must have string, integer or encoding.TextUnmarshaler keys but got: %s key in %s", keyType.Kind(), ifv.Type()))
 		}
 
 		if len(ifv.MapKeys()) != 0 {
 			for _, k := range ifv.MapKeys() {
-				key := k.String()
+				key := mapKeyString(k)
 				newPrefix := ""
 				if prefix != "" {
 					newPrefix = strings.Join([]string{prefix, key}, ".")
@@ -281,7 +498,7 @@ func deepFieldNames(iface interface{}, prefix string, inMap bool) ([]string, err
 					newPrefix = key
 				}
 
-				fieldNames, err := deepFieldNames(ifv.MapIndex(k).Interface(), strings.ToLower(newPrefix), inMap)
+				fieldNames, err := deepFieldNames(ifv.MapIndex(k).Interface(), strings.ToLower(newPrefix))
 				if err != nil {
 					return nil, err
 				}
@@ -297,13 +514,12 @@ func deepFieldNames(iface interface{}, prefix string, inMap bool) ([]string, err
 
 			e := ifv.Type().Elem()
 			value := reflect.Zero(e)
-			fieldNames, err := deepFieldNames(value.Interface(), strings.ToLower(newPrefix), inMap)
+			fieldNames, err := deepFieldNames(value.Interface(), strings.ToLower(newPrefix))
 			if err != nil {
 				return nil, err
 			}
 			names = append(names, fieldNames...)
 		}
-		inMap = false
 	default:
 		if prefix != "" {
 			names = append(names, strings.ToLower(prefix))
@@ -316,7 +532,7 @@ func deepFieldNames(iface interface{}, prefix string, inMap bool) ([]string, err
 // ToYaml returns yaml marshalled struct
 func (i *insConfigurator) ToYaml(c interface{}) string {
 	// todo clean password
-	out, err := yaml.Marshal(c)
+	out, err := yaml.Marshal(toYamlValue(reflect.ValueOf(c)))
 	if err != nil {
 		return fmt.Sprintf("failed to marshal config structure: %v", err)
 	}
@@ -383,8 +599,8 @@ func (m *YamlTemplater) TemplateTo(w io.Writer) error {
 			c = arr[0]
 		}
 	}
-	if cont, ok := m.Tag.Lookup("yaml"); ok {
-		yfname = cont
+	if m.FName != "" {
+		yfname = namingFromTag(m.FName, m.Tag).Name
 	}
 
 	if c != "" { // write down a comment
@@ -406,14 +622,18 @@ func (m *YamlTemplater) TemplateTo(w io.Writer) error {
 		if _, err := fmt.Fprint(w, "\n"); err != nil {
 			return err
 		}
-		for i := 0; i < t.NumField(); i++ {
+		for _, f := range visibleFields(t, v) {
+			obj := reflect.Zero(f.field.Type)
+			if f.value.IsValid() {
+				obj = f.value
+			}
 			if err := (&YamlTemplater{
-				Obj:   v.Field(i).Interface(),
+				Obj:   obj.Interface(),
 				Level: m.Level + 1,
-				Tag:   t.Field(i).Tag,
-				FName: t.Field(i).Name,
+				Tag:   f.field.Tag,
+				FName: f.field.Name,
 			}).TemplateTo(w); err != nil {
-				return errors.Wrapf(err, "in field %s", t.Field(i).Name)
+				return errors.Wrapf(err, "in field %s", f.field.Name)
 			}
 		}
 
@@ -458,10 +678,13 @@ type YamlDumpable interface {
 }
 
 type YamlDumper struct {
-	Obj   interface{}       // what are we marshaling right now
-	Level int               // Level of recursion
-	Tag   reflect.StructTag // Tag for current field
-	FName string            // current field name
+	Obj     interface{}       // what are we marshaling right now
+	Level   int               // Level of recursion
+	Tag     reflect.StructTag // Tag for current field
+	FName   string            // current field name
+	Sources map[string]Source // sources of values to write down as comments, optional
+
+	key string // config key of current field
 }
 
 func NewYamlDumper(obj interface{}) *YamlDumper {
@@ -472,8 +695,38 @@ func NewYamlDumper(obj interface{}) *YamlDumper {
 	}
 }
 
+// NewYamlDumperWithSources creates dumper which annotates values with their sources, see Provenance
+func NewYamlDumperWithSources(obj interface{}, sources map[string]Source) *YamlDumper {
+	return &YamlDumper{
+		Obj:     obj,
+		Level:   0,
+		Tag:     "",
+		Sources: sources,
+	}
+}
+
+func (d *YamlDumper) sourceComment() string {
+	if source, ok := d.Sources[d.key]; ok {
+		return fmt.Sprintf(" # from: %s", source)
+	}
+	return ""
+}
+
+func joinKey(prefix, key string) string {
+	key = strings.ToLower(key)
+	if prefix == "" {
+		return key
+	}
+	return strings.Join([]string{prefix, key}, ".")
+}
+
 func (d *YamlDumper) DumpTo(w io.Writer) error {
 
+	if _, ok := d.Tag.Lookup("insconfigsecret"); ok { // values of any kind are masked
+		_, err := fmt.Fprintf(w, "\"*****\"%s\n", d.sourceComment())
+		return err
+	}
+
 	if o, ok := d.Obj.(YamlDumpable); ok {
 		return o.DumpTo(w, d)
 	}
@@ -482,59 +735,61 @@ func (d *YamlDumper) DumpTo(w io.Writer) error {
 	v := reflect.ValueOf(d.Obj)
 
 	if t.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			_, err := fmt.Fprintf(w, "null%s\n", d.sourceComment())
+			return err
+		}
 		d.Obj = v.Elem().Interface()
 		return d.DumpTo(w)
 	}
 
-	if _, ok := d.Tag.Lookup("insconfigsecret"); ok { // detect tags
-		v = reflect.ValueOf(`"*****"`)
-	}
-
 	indent := strings.Repeat("  ", d.Level)
 
 	switch t.Kind() { // main switch
 	case reflect.Struct: // no default
-		fmt.Fprint(w, "\n")
-		for i := 0; i < t.NumField(); i++ {
-			v := v.Field(i)
-			t := t.Field(i)
-			yfname := ""
-			if cont, ok := t.Tag.Lookup("yaml"); ok {
-				yfname = cont
-			} else {
-				yfname = strings.ToLower(t.Name)
+		fmt.Fprint(w, d.sourceComment()+"\n")
+		for _, f := range visibleFields(t, v) {
+			if !f.value.IsValid() || f.naming.OmitEmpty && f.value.IsZero() {
+				continue
 			}
-			fmt.Fprintf(w, "%s%s: ", indent, yfname)
+			fmt.Fprintf(w, "%s%s: ", indent, f.naming.Name)
 			if err := (&YamlDumper{
-				Obj:   v.Interface(),
-				Level: d.Level + 1,
-				Tag:   t.Tag,
-				FName: t.Name,
+				Obj:     f.value.Interface(),
+				Level:   d.Level + 1,
+				Tag:     f.field.Tag,
+				FName:   f.field.Name,
+				Sources: d.Sources,
+				key:     joinKey(d.key, f.naming.Name),
 			}).DumpTo(w); err != nil {
-				return errors.Wrapf(err, "in field %s", t.Name)
+				return errors.Wrapf(err, "in field %s", f.field.Name)
 			}
 		}
 
 	case reflect.Map:
-		fmt.Fprint(w, "\n")
+		fmt.Fprint(w, d.sourceComment()+"\n")
 		i := v.MapRange()
 		for i.Next() {
-			fmt.Fprintf(w, "%s%s: ", indent, i.Key().Interface())
+			mapKey := mapKeyString(i.Key())
+			fmt.Fprintf(w, "%s%s: ", indent, mapKey)
 			if err := (&YamlDumper{
-				Obj:   i.Value().Interface(),
-				Level: d.Level + 1,
+				Obj:     i.Value().Interface(),
+				Level:   d.Level + 1,
+				Sources: d.Sources,
+				key:     joinKey(d.key, mapKey),
 			}).DumpTo(w); err != nil {
 				return err
 			}
 		}
 
 	case reflect.Array, reflect.Slice:
-		fmt.Fprint(w, "\n")
+		fmt.Fprint(w, d.sourceComment()+"\n")
 		for i := 0; i < v.Len(); i++ {
 			fmt.Fprintf(w, "%s - ", indent)
 			if err := (&YamlDumper{
-				Obj:   v.Index(i).Interface(),
-				Level: d.Level + 1,
+				Obj:     v.Index(i).Interface(),
+				Level:   d.Level + 1,
+				Sources: d.Sources,
+				key:     joinKey(d.key, strconv.Itoa(i)),
 			}).DumpTo(w); err != nil {
 				return err
 			}
@@ -545,7 +800,7 @@ func (d *YamlDumper) DumpTo(w io.Writer) error {
 		reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16,
 		reflect.Uint32, reflect.Uint64, reflect.Uintptr, reflect.Float32, reflect.Float64,
 		reflect.Complex64, reflect.Complex128:
-		_, err := fmt.Fprintf(w, "%v\n", v.Interface())
+		_, err := fmt.Fprintf(w, "%v%s\n", v.Interface(), d.sourceComment())
 		return err
 	}
 
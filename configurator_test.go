@@ -3,10 +3,16 @@ package insconfig_test
 import (
 	"bytes"
 	"errors"
+	goflag "flag"
+	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v2"
 
@@ -33,6 +39,29 @@ type anonymousEmbeddedStruct struct {
 	Level4    string
 }
 
+// ShardID is a map key decoded from text like "eu-1"
+type ShardID struct {
+	Region string
+	Num    int
+}
+
+func (s *ShardID) UnmarshalText(text []byte) error {
+	parts := strings.SplitN(string(text), "-", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("wrong shard id %q", text)
+	}
+	num, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return fmt.Errorf("wrong shard id %q", text)
+	}
+	*s = ShardID{Region: parts[0], Num: num}
+	return nil
+}
+
+func (s ShardID) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%s-%d", s.Region, s.Num)), nil
+}
+
 type testPathGetter struct {
 	Path string
 }
@@ -41,6 +70,16 @@ func (g testPathGetter) GetConfigPath() string {
 	return g.Path
 }
 
+type testPathsGetter []string
+
+func (g testPathsGetter) GetConfigPath() string {
+	return ""
+}
+
+func (g testPathsGetter) GetConfigPaths() []string {
+	return g
+}
+
 func Test_Load(t *testing.T) {
 	t.Run("yaml", func(t *testing.T) {
 		t.Run("happy", func(t *testing.T) {
@@ -136,6 +175,197 @@ func Test_Load(t *testing.T) {
 		})
 	})
 
+	t.Run("layers", func(t *testing.T) {
+		t.Run("happy", func(t *testing.T) {
+			cfg := CfgStruct{}
+			params := insconfig.Params{
+				EnvPrefix:        "testprefix",
+				ConfigPathGetter: testPathsGetter{"testdata/layers/base.yaml", "testdata/layers/overlay.yaml"},
+			}
+
+			insConfigurator := insconfig.New(params)
+			err := insConfigurator.Load(&cfg)
+			require.NoError(t, err)
+			require.Equal(t, "overlay1", cfg.Level1text)
+			require.Equal(t, "overlay2", cfg.Level2.Level2text)
+			require.Equal(t, "base3", cfg.Level2.Level3.Level3text)
+			require.Len(t, cfg.MapField, 2)
+			require.Equal(t, "key1text2", cfg.MapField["key1"].Level2text)
+			require.Equal(t, "key2text2", cfg.MapField["key2"].Level2text)
+			require.Len(t, cfg.Map2, 1)
+		})
+
+		t.Run("fail not enough keys in merged config", func(t *testing.T) {
+			cfg := CfgStruct{}
+			params := insconfig.Params{
+				EnvPrefix:        "testprefix",
+				ConfigPathGetter: testPathsGetter{"testdata/layers/base.yaml"},
+			}
+
+			insConfigurator := insconfig.New(params)
+			err := insConfigurator.Load(&cfg)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "level1text")
+		})
+
+		t.Run("fail extra in overlay", func(t *testing.T) {
+			cfg := CfgStruct{}
+			params := insconfig.Params{
+				EnvPrefix:        "testprefix",
+				ConfigPathGetter: testPathsGetter{"testdata/layers/base.yaml", "testdata/layers/overlay_wrong.yaml"},
+			}
+
+			insConfigurator := insconfig.New(params)
+			err := insConfigurator.Load(&cfg)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "nonexistent")
+		})
+
+		t.Run("fail key duplication in overlay", func(t *testing.T) {
+			cfg := CfgStruct{}
+			params := insconfig.Params{
+				EnvPrefix:        "testprefix",
+				ConfigPathGetter: testPathsGetter{"testdata/layers/base.yaml", "testdata/layers/overlay_duplication.yaml"},
+			}
+
+			insConfigurator := insconfig.New(params)
+			err := insConfigurator.Load(&cfg)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "overlay_duplication.yaml")
+			require.Contains(t, err.Error(), `key "key2" already set in map`)
+		})
+
+		t.Run("fail required overlay not found", func(t *testing.T) {
+			cfg := CfgStruct{}
+			params := insconfig.Params{
+				EnvPrefix:        "testprefix",
+				ConfigPathGetter: testPathsGetter{"testdata/layers/base.yaml", "nonexistent.yaml"},
+			}
+
+			insConfigurator := insconfig.New(params)
+			err := insConfigurator.Load(&cfg)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "nonexistent.yaml")
+		})
+	})
+
+	t.Run("config directory", func(t *testing.T) {
+		t.Run("happy", func(t *testing.T) {
+			cfg := CfgStruct{}
+			params := insconfig.Params{
+				EnvPrefix:        "testprefix",
+				ConfigPathGetter: testPathGetter{"testdata/confd"},
+			}
+
+			insConfigurator := insconfig.New(params)
+			err := insConfigurator.Load(&cfg)
+			require.NoError(t, err)
+			require.Equal(t, "text1", cfg.Level1text)
+			require.Equal(t, "overridden", cfg.Level2.Level2text)
+			require.Equal(t, "text3", cfg.Level2.Level3.Level3text)
+			require.Len(t, cfg.MapField, 1)
+			require.Len(t, cfg.Map2, 1)
+		})
+
+		t.Run("empty directory not required", func(t *testing.T) {
+			cfg := CfgStruct{}
+			params := insconfig.Params{
+				EnvPrefix:        "testprefix",
+				ConfigPathGetter: testPathsGetter{"testdata/confd", t.TempDir()},
+				FileNotRequired:  true,
+			}
+
+			insConfigurator := insconfig.New(params)
+			err := insConfigurator.Load(&cfg)
+			require.NoError(t, err)
+			require.Equal(t, "overridden", cfg.Level2.Level2text)
+		})
+
+		t.Run("fail not enough keys reports fragment", func(t *testing.T) {
+			cfg := CfgStruct{}
+			params := insconfig.Params{
+				EnvPrefix:        "testprefix",
+				ConfigPathGetter: testPathGetter{"testdata/confd_wrong"},
+			}
+
+			insConfigurator := insconfig.New(params)
+			err := insConfigurator.Load(&cfg)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "mapfield.key2.level3.level3text (mapfield.key2 is defined in testdata/confd_wrong/20-maps.yaml)")
+		})
+
+		t.Run("fail extra reports fragment", func(t *testing.T) {
+			cfg := CfgStruct{}
+			params := insconfig.Params{
+				EnvPrefix:        "testprefix",
+				ConfigPathGetter: testPathsGetter{"testdata/confd", "testdata/confd_extra.yaml"},
+			}
+
+			insConfigurator := insconfig.New(params)
+			err := insConfigurator.Load(&cfg)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "level2.nonexistent (defined in file testdata/confd_extra.yaml:2:3)")
+		})
+	})
+
+	t.Run("include", func(t *testing.T) {
+		t.Run("happy", func(t *testing.T) {
+			cfg := CfgStruct{}
+			params := insconfig.Params{
+				EnvPrefix:        "testprefix",
+				ConfigPathGetter: testPathGetter{"testdata/include/main.yaml"},
+			}
+
+			insConfigurator := insconfig.New(params)
+			err := insConfigurator.Load(&cfg)
+			require.NoError(t, err)
+			require.Equal(t, "main1", cfg.Level1text)
+			require.Equal(t, "common2", cfg.Level2.Level2text)
+			require.Equal(t, "key1text2", cfg.MapField["key1"].Level2text)
+			require.Equal(t, "textmap2l3", cfg.Map2["key3"].Level3text)
+		})
+
+		t.Run("fail cycle", func(t *testing.T) {
+			cfg := CfgStruct{}
+			params := insconfig.Params{
+				EnvPrefix:        "testprefix",
+				ConfigPathGetter: testPathGetter{"testdata/include/cycle_a.yaml"},
+			}
+
+			insConfigurator := insconfig.New(params)
+			err := insConfigurator.Load(&cfg)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "config files include cycle: testdata/include/cycle_a.yaml -> testdata/include/cycle_b.yaml -> testdata/include/cycle_a.yaml")
+		})
+
+		t.Run("fail self include", func(t *testing.T) {
+			cfg := CfgStruct{}
+			params := insconfig.Params{
+				EnvPrefix:        "testprefix",
+				ConfigPathGetter: testPathGetter{"testdata/include/self.yaml"},
+			}
+
+			insConfigurator := insconfig.New(params)
+			err := insConfigurator.Load(&cfg)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "config files include cycle")
+		})
+
+		t.Run("fail key duplication in included file", func(t *testing.T) {
+			cfg := CfgStruct{}
+			params := insconfig.Params{
+				EnvPrefix:        "testprefix",
+				ConfigPathGetter: testPathGetter{"testdata/include/duplication.yaml"},
+			}
+
+			insConfigurator := insconfig.New(params)
+			err := insConfigurator.Load(&cfg)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "duplication_included.yaml")
+			require.Contains(t, err.Error(), `key "key3" already set in map`)
+		})
+	})
+
 	t.Run("env", func(t *testing.T) {
 		t.Run("happy", func(t *testing.T) {
 			_ = os.Setenv("TESTPREFIX_LEVEL1TEXT", "newTextValue1")
@@ -477,7 +707,7 @@ func Test_Load(t *testing.T) {
 			require.Equal(t, "1", cfg["num"])
 		})
 
-		t.Run("fail two nested maps yaml", func(t *testing.T) {
+		t.Run("two nested maps yaml", func(t *testing.T) {
 			type TwoNested struct {
 				One map[string]map[string]MapValue
 			}
@@ -491,11 +721,11 @@ func Test_Load(t *testing.T) {
 
 			insConfigurator := insconfig.New(params)
 			err := insConfigurator.Load(&cfg)
-			require.Error(t, err)
-			require.Equal(t, "nested maps are not allowed in config", err.Error())
+			require.NoError(t, err)
+			require.Equal(t, MapValue{Str: "first-str", Num: 1, Flag: true}, cfg.One["first"]["first"])
 		})
 
-		t.Run("fail two nested maps env", func(t *testing.T) {
+		t.Run("two nested maps env", func(t *testing.T) {
 			_ = os.Setenv("TESTPREFIX_ONE_FIRST_FIRST_STR", "first-str")
 			_ = os.Setenv("TESTPREFIX_ONE_FIRST_FIRST_NUM", "1")
 			_ = os.Setenv("TESTPREFIX_ONE_FIRST_FIRST_FLAG", "true")
@@ -516,11 +746,11 @@ func Test_Load(t *testing.T) {
 
 			insConfigurator := insconfig.New(params)
 			err := insConfigurator.Load(&cfg)
-			require.Error(t, err)
-			require.Equal(t, "nested maps are not allowed in config", err.Error())
+			require.NoError(t, err)
+			require.Equal(t, MapValue{Str: "first-str", Num: 1, Flag: true}, cfg.One["first"]["first"])
 		})
 
-		t.Run("fail map-struct-map yaml", func(t *testing.T) {
+		t.Run("map-struct-map yaml", func(t *testing.T) {
 			type StructMap struct {
 				Two map[string]MapValue
 			}
@@ -535,13 +765,103 @@ func Test_Load(t *testing.T) {
 				FileNotRequired:  false,
 			}
 
+			insConfigurator := insconfig.New(params)
+			err := insConfigurator.Load(&cfg)
+			require.NoError(t, err)
+			require.Equal(t, MapValue{Str: "first-first", Num: 1, Flag: true}, cfg.One["first"].Two["first"])
+		})
+
+		t.Run("nested maps env several entries", func(t *testing.T) {
+			_ = os.Setenv("TESTPREFIX_ONE_EU_API_STR", "eu-api")
+			_ = os.Setenv("TESTPREFIX_ONE_EU_API_NUM", "1")
+			_ = os.Setenv("TESTPREFIX_ONE_EU_API_FLAG", "true")
+			_ = os.Setenv("TESTPREFIX_ONE_US_WEB_APP_STR", "us-web-app")
+			_ = os.Setenv("TESTPREFIX_ONE_US_WEB_APP_NUM", "2")
+			_ = os.Setenv("TESTPREFIX_ONE_US_WEB_APP_FLAG", "false")
+			defer os.Unsetenv("TESTPREFIX_ONE_EU_API_STR")
+			defer os.Unsetenv("TESTPREFIX_ONE_EU_API_NUM")
+			defer os.Unsetenv("TESTPREFIX_ONE_EU_API_FLAG")
+			defer os.Unsetenv("TESTPREFIX_ONE_US_WEB_APP_STR")
+			defer os.Unsetenv("TESTPREFIX_ONE_US_WEB_APP_NUM")
+			defer os.Unsetenv("TESTPREFIX_ONE_US_WEB_APP_FLAG")
+
+			type TwoNested struct {
+				One map[string]map[string]MapValue
+			}
+
+			cfg := TwoNested{}
+			params := insconfig.Params{
+				EnvPrefix:        "testprefix",
+				ConfigPathGetter: testPathGetter{""},
+				FileNotRequired:  true,
+			}
+
+			insConfigurator := insconfig.New(params)
+			err := insConfigurator.Load(&cfg)
+			require.NoError(t, err)
+			require.Equal(t, MapValue{Str: "eu-api", Num: 1, Flag: true}, cfg.One["eu"]["api"])
+			require.Equal(t, MapValue{Str: "us-web-app", Num: 2, Flag: false}, cfg.One["us"]["web_app"])
+		})
+
+		t.Run("map-struct-map env entry with sibling", func(t *testing.T) {
+			_ = os.Setenv("TESTPREFIX_ONE_FIRST_NAME", "first")
+			_ = os.Setenv("TESTPREFIX_ONE_FIRST_TWO_SECOND_STR", "first-second")
+			_ = os.Setenv("TESTPREFIX_ONE_FIRST_TWO_SECOND_NUM", "2")
+			_ = os.Setenv("TESTPREFIX_ONE_FIRST_TWO_SECOND_FLAG", "true")
+			defer os.Unsetenv("TESTPREFIX_ONE_FIRST_NAME")
+			defer os.Unsetenv("TESTPREFIX_ONE_FIRST_TWO_SECOND_STR")
+			defer os.Unsetenv("TESTPREFIX_ONE_FIRST_TWO_SECOND_NUM")
+			defer os.Unsetenv("TESTPREFIX_ONE_FIRST_TWO_SECOND_FLAG")
+
+			type StructMap struct {
+				Name string
+				Two  map[string]MapValue
+			}
+			type MapStructMap struct {
+				One map[string]StructMap
+			}
+
+			cfg := MapStructMap{}
+			params := insconfig.Params{
+				EnvPrefix:        "testprefix",
+				ConfigPathGetter: testPathGetter{""},
+				FileNotRequired:  true,
+			}
+
+			insConfigurator := insconfig.New(params)
+			err := insConfigurator.Load(&cfg)
+			require.NoError(t, err)
+			require.Equal(t, "first", cfg.One["first"].Name)
+			require.Equal(t, MapValue{Str: "first-second", Num: 2, Flag: true}, cfg.One["first"].Two["second"])
+		})
+
+		t.Run("fail map-struct-map env incomplete entry", func(t *testing.T) {
+			_ = os.Setenv("TESTPREFIX_ONE_FIRST_NAME", "first")
+			defer os.Unsetenv("TESTPREFIX_ONE_FIRST_NAME")
+
+			type StructMap struct {
+				Name string
+				Two  map[string]MapValue
+			}
+			type MapStructMap struct {
+				One map[string]StructMap
+			}
+
+			cfg := MapStructMap{}
+			params := insconfig.Params{
+				EnvPrefix:        "testprefix",
+				ConfigPathGetter: testPathGetter{""},
+				FileNotRequired:  true,
+			}
+
 			insConfigurator := insconfig.New(params)
 			err := insConfigurator.Load(&cfg)
 			require.Error(t, err)
-			require.Equal(t, "nested maps are not allowed in config", err.Error())
+			require.Contains(t, err.Error(), "one.first.two.<-key->.str")
+			require.NotContains(t, err.Error(), "one.<-key->")
 		})
 
-		t.Run("fail map-struct-map env", func(t *testing.T) {
+		t.Run("map-struct-map env", func(t *testing.T) {
 			_ = os.Setenv("TESTPREFIX_ONE_FIRST_TWO_FIRST_STR", "first-first")
 			_ = os.Setenv("TESTPREFIX_ONE_FIRST_TWO_FIRST_NUM", "1")
 			_ = os.Setenv("TESTPREFIX_ONE_FIRST_TWO_FIRST_FLAG", "true")
@@ -565,11 +885,11 @@ func Test_Load(t *testing.T) {
 
 			insConfigurator := insconfig.New(params)
 			err := insConfigurator.Load(&cfg)
-			require.Error(t, err)
-			require.Equal(t, "nested maps are not allowed in config", err.Error())
+			require.NoError(t, err)
+			require.Equal(t, MapValue{Str: "first-first", Num: 1, Flag: true}, cfg.One["first"].Two["first"])
 		})
 
-		t.Run("fail one map int key", func(t *testing.T) {
+		t.Run("one map int key", func(t *testing.T) {
 			type MapIntKey struct {
 				One map[int]MapValue
 			}
@@ -580,10 +900,68 @@ func Test_Load(t *testing.T) {
 				FileNotRequired:  false,
 			}
 
+			insConfigurator := insconfig.New(params)
+			err := insConfigurator.Load(&cfg)
+			require.NoError(t, err)
+			require.Equal(t, MapValue{Str: "first-str", Num: 1, Flag: true}, cfg.One[1])
+			require.Equal(t, MapValue{Str: "second-str", Num: 2, Flag: false}, cfg.One[2])
+		})
+
+		t.Run("map uint key env", func(t *testing.T) {
+			_ = os.Setenv("TESTPREFIX_ONE_8080_STR", "http")
+			_ = os.Setenv("TESTPREFIX_ONE_8080_NUM", "1")
+			_ = os.Setenv("TESTPREFIX_ONE_8080_FLAG", "true")
+			defer os.Unsetenv("TESTPREFIX_ONE_8080_STR")
+			defer os.Unsetenv("TESTPREFIX_ONE_8080_NUM")
+			defer os.Unsetenv("TESTPREFIX_ONE_8080_FLAG")
+
+			type MapUintKey struct {
+				One map[uint16]MapValue
+			}
+			cfg := MapUintKey{}
+			params := insconfig.Params{
+				EnvPrefix:        "testprefix",
+				ConfigPathGetter: testPathGetter{""},
+				FileNotRequired:  true,
+			}
+
+			insConfigurator := insconfig.New(params)
+			err := insConfigurator.Load(&cfg)
+			require.NoError(t, err)
+			require.Equal(t, MapValue{Str: "http", Num: 1, Flag: true}, cfg.One[8080])
+		})
+
+		t.Run("map text unmarshaler key", func(t *testing.T) {
+			type MapShardKey struct {
+				One map[ShardID]MapValue
+			}
+			cfg := MapShardKey{}
+			params := insconfig.Params{
+				EnvPrefix:        "testprefix",
+				ConfigPathGetter: testPathGetter{"testdata/test_config_map_shard_key.yaml"},
+			}
+
+			insConfigurator := insconfig.New(params)
+			err := insConfigurator.Load(&cfg)
+			require.NoError(t, err)
+			require.Equal(t, MapValue{Str: "first-str", Num: 1, Flag: true}, cfg.One[ShardID{Region: "eu", Num: 1}])
+			require.Equal(t, MapValue{Str: "second-str", Num: 2, Flag: false}, cfg.One[ShardID{Region: "us", Num: 2}])
+		})
+
+		t.Run("fail map wrong text unmarshaler key", func(t *testing.T) {
+			type MapShardKey struct {
+				One map[ShardID]MapValue
+			}
+			cfg := MapShardKey{}
+			params := insconfig.Params{
+				EnvPrefix:        "testprefix",
+				ConfigPathGetter: testPathGetter{"testdata/test_config_map_wrong_shard_key.yaml"},
+			}
+
 			insConfigurator := insconfig.New(params)
 			err := insConfigurator.Load(&cfg)
 			require.Error(t, err)
-			require.Contains(t, err.Error(), "maps in config must have string keys but got:")
+			require.Contains(t, err.Error(), "wrong shard id")
 		})
 
 		t.Run("fail one map struct key", func(t *testing.T) {
@@ -600,7 +978,7 @@ func Test_Load(t *testing.T) {
 			insConfigurator := insconfig.New(params)
 			err := insConfigurator.Load(&cfg)
 			require.Error(t, err)
-			require.Contains(t, err.Error(), "maps in config must have string keys but got:")
+			require.Contains(t, err.Error(), "maps in config must have string, integer or encoding.TextUnmarshaler keys but got:")
 		})
 
 		t.Run("fail key duplication", func(t *testing.T) {
@@ -620,6 +998,51 @@ func Test_Load(t *testing.T) {
 	})
 }
 
+func Test_LoadTwice(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("level1text: text1\nnumber: 5\nlevel2:\n  level2text: text2\n  level3:\n    level3text: text3\n    nullstring: null\n"), 0o600))
+	params := insconfig.Params{
+		EnvPrefix:        "testprefix",
+		ConfigPathGetter: testPathGetter{path},
+	}
+	insConfigurator := insconfig.New(params)
+	require.NoError(t, insConfigurator.Load(&ErrorsCfg{}))
+
+	require.NoError(t, os.WriteFile(path, []byte("level1text: text1\nlevel2:\n  level2text: text2\n  level3:\n    level3text: text3\n    nullstring: null\n"), 0o600))
+	cfg := ErrorsCfg{}
+	err := insConfigurator.Load(&cfg)
+	var missingErr *insconfig.MissingKeysError
+	require.True(t, errors.As(err, &missingErr), err)
+	require.Equal(t, []string{"number"}, missingErr.Keys)
+	require.Equal(t, 0, cfg.Number)
+}
+
+func Test_Provenance(t *testing.T) {
+	_ = os.Setenv("TESTPREFIX_LEVEL2_LEVEL2TEXT", "newTextValue")
+	defer os.Unsetenv("TESTPREFIX_LEVEL2_LEVEL2TEXT")
+
+	cfg := CfgStruct{}
+	params := insconfig.Params{
+		EnvPrefix:        "testprefix",
+		ConfigPathGetter: testPathGetter{"testdata/test_config.yaml"},
+	}
+
+	insConfigurator := insconfig.New(params)
+	err := insConfigurator.Load(&cfg)
+	require.NoError(t, err)
+
+	sources := insConfigurator.Provenance()
+	require.Equal(t, insconfig.Source{Kind: insconfig.SourceFile, Name: "testdata/test_config.yaml", Line: 1, Column: 13}, sources["level1text"])
+	require.Equal(t, insconfig.Source{Kind: insconfig.SourceFile, Name: "testdata/test_config.yaml", Line: 16, Column: 19}, sources["mapfield.key2.level3.level3text"])
+	require.Equal(t, insconfig.Source{Kind: insconfig.SourceEnv, Name: "TESTPREFIX_LEVEL2_LEVEL2TEXT"}, sources["level2.level2text"])
+
+	w := &bytes.Buffer{}
+	err = insconfig.NewYamlDumperWithSources(cfg, sources).DumpTo(w)
+	require.NoError(t, err)
+	require.Contains(t, w.String(), "level1text: text1 # from: file testdata/test_config.yaml:1:13\n")
+	require.Contains(t, w.String(), "level2text: newTextValue # from: env TESTPREFIX_LEVEL2_LEVEL2TEXT\n")
+}
+
 type Y struct {
 	F int `insconfig:"111| the F comment"`
 }
@@ -693,3 +1116,298 @@ func Test_DumpTo(t *testing.T) {
 	x2.B = x.B
 	require.Equal(t, x, x2)
 }
+
+type SeparatorCfg struct {
+	Host struct {
+		MaxConn int `mapstructure:"max_conn"`
+		Max     struct {
+			Conn int
+		}
+	}
+	Routes map[string]map[string]string
+}
+
+func Test_DumpToMapKeys(t *testing.T) {
+	type MapKeysCfg struct {
+		Ports  map[uint16]Y
+		Shards map[ShardID]Y
+	}
+	cfg := MapKeysCfg{
+		Ports:  map[uint16]Y{443: {1}},
+		Shards: map[ShardID]Y{{Region: "eu", Num: 1}: {2}},
+	}
+	sources := map[string]insconfig.Source{
+		"ports.443.f":   {Kind: insconfig.SourceEnv, Name: "TESTPREFIX_PORTS_443_F"},
+		"shards.eu-1.f": {Kind: insconfig.SourceFile, Name: "config.yaml", Line: 3, Column: 8},
+	}
+
+	w := &bytes.Buffer{}
+	require.NoError(t, insconfig.NewYamlDumperWithSources(cfg, sources).DumpTo(w))
+	require.Contains(t, w.String(), "  443: \n    f: 1 # from: env TESTPREFIX_PORTS_443_F\n")
+	require.Contains(t, w.String(), "  eu-1: \n    f: 2 # from: file config.yaml:3:8\n")
+
+	cfg2 := MapKeysCfg{}
+	require.NoError(t, yaml.Unmarshal(w.Bytes(), &cfg2))
+	require.Equal(t, cfg.Ports, cfg2.Ports)
+}
+
+func Test_EnvSeparator(t *testing.T) {
+	t.Run("custom separator", func(t *testing.T) {
+		_ = os.Setenv("TESTPREFIX_HOST__MAX_CONN", "5")
+		_ = os.Setenv("TESTPREFIX_HOST__MAX__CONN", "6")
+		_ = os.Setenv("TESTPREFIX_ROUTES__EU_WEST__API", "api:80")
+		defer os.Unsetenv("TESTPREFIX_HOST__MAX_CONN")
+		defer os.Unsetenv("TESTPREFIX_HOST__MAX__CONN")
+		defer os.Unsetenv("TESTPREFIX_ROUTES__EU_WEST__API")
+
+		cfg := SeparatorCfg{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			EnvSeparator:     "__",
+			ConfigPathGetter: testPathGetter{""},
+			FileNotRequired:  true,
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		require.NoError(t, err)
+		require.Equal(t, 5, cfg.Host.MaxConn)
+		require.Equal(t, 6, cfg.Host.Max.Conn)
+		require.Equal(t, "api:80", cfg.Routes["eu_west"]["api"])
+	})
+
+	t.Run("fail unknown key with custom separator", func(t *testing.T) {
+		_ = os.Setenv("TESTPREFIX_HOST__MIN_CONN", "5")
+		defer os.Unsetenv("TESTPREFIX_HOST__MIN_CONN")
+
+		cfg := SeparatorCfg{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			EnvSeparator:     "__",
+			ConfigPathGetter: testPathGetter{""},
+			FileNotRequired:  true,
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		var envErr *insconfig.UnknownEnvKeysError
+		require.True(t, errors.As(err, &envErr), err)
+		require.Equal(t, []string{"host.min_conn"}, envErr.Keys)
+	})
+
+	t.Run("map keys containing separator", func(t *testing.T) {
+		type MapsCfg struct {
+			M map[string]map[string]string
+		}
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("m:\n  a_b:\n    c: first\n  a:\n    b_c: second\n"), 0o600))
+
+		cfg := MapsCfg{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{path},
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		require.NoError(t, err)
+		require.Equal(t, map[string]map[string]string{"a_b": {"c": "first"}, "a": {"b_c": "second"}}, cfg.M)
+	})
+
+	t.Run("fail ambiguous default separator", func(t *testing.T) {
+		cfg := SeparatorCfg{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{""},
+			FileNotRequired:  true,
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		var ambiguousErr *insconfig.AmbiguousEnvKeysError
+		require.True(t, errors.As(err, &ambiguousErr), err)
+		require.Equal(t, []string{"TESTPREFIX_HOST_MAX_CONN"}, ambiguousErr.Vars)
+		require.Equal(t, []string{"host.max.conn", "host.max_conn"}, ambiguousErr.Keys["TESTPREFIX_HOST_MAX_CONN"])
+		require.EqualError(t, err, "ENV names are ambiguous, set another EnvSeparator: TESTPREFIX_HOST_MAX_CONN (host.max.conn, host.max_conn)")
+	})
+}
+
+type testGenConfigGetter struct {
+	testPathGetter
+	GenPath string
+}
+
+func (g testGenConfigGetter) GetGenConfigPath() (string, bool) {
+	return g.GenPath, true
+}
+
+func Test_GenConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	cfg := ErrorsCfg{Level1text: "default text", Number: 5}
+	params := insconfig.Params{
+		EnvPrefix:        "testprefix",
+		ConfigPathGetter: testGenConfigGetter{testPathGetter{"nonexistent.yaml"}, path},
+	}
+
+	insConfigurator := insconfig.New(params)
+	err := insConfigurator.Load(&cfg)
+	require.True(t, errors.Is(err, insconfig.ErrConfigGenerated), err)
+
+	template, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Contains(t, string(template), "level1text: default text # string\n")
+	require.Contains(t, string(template), "number: 5 # int\n")
+}
+
+// setArgs replaces command line arguments and flags parsed by the path getters for the test
+func setArgs(t *testing.T, args ...string) {
+	osArgs, commandLine := os.Args, pflag.CommandLine
+	os.Args = append([]string{"app"}, args...)
+	pflag.CommandLine = pflag.NewFlagSet("app", pflag.ContinueOnError)
+	t.Cleanup(func() {
+		os.Args, pflag.CommandLine = osArgs, commandLine
+	})
+}
+
+func pathGetters() map[string]func() insconfig.ConfigPathGetter {
+	return map[string]func() insconfig.ConfigPathGetter{
+		"DefaultPathGetter": func() insconfig.ConfigPathGetter {
+			return &insconfig.DefaultPathGetter{}
+		},
+		"FlagPathGetter": func() insconfig.ConfigPathGetter {
+			return &insconfig.FlagPathGetter{GoFlags: goflag.NewFlagSet("app", goflag.ContinueOnError)}
+		},
+		"PFlagPathGetter": func() insconfig.ConfigPathGetter {
+			return &insconfig.PFlagPathGetter{PFlags: pflag.NewFlagSet("app", pflag.ContinueOnError)}
+		},
+	}
+}
+
+func Test_GenConfigFlag(t *testing.T) {
+	for name, getter := range pathGetters() {
+		t.Run(name+" without path", func(t *testing.T) {
+			setArgs(t, "--config", "nonexistent.yaml", "--gen-config")
+			cfg := ErrorsCfg{Level1text: "default text", Number: 5}
+			out := &bytes.Buffer{}
+			params := insconfig.Params{
+				EnvPrefix:        "testprefix",
+				ConfigPathGetter: getter(),
+				Output:           out,
+			}
+
+			insConfigurator := insconfig.New(params)
+			err := insConfigurator.Load(&cfg)
+			require.True(t, errors.Is(err, insconfig.ErrConfigGenerated), err)
+			require.Contains(t, out.String(), "level1text: default text # string\n")
+		})
+
+		t.Run(name+" with path", func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			setArgs(t, "--config", "nonexistent.yaml", "--gen-config="+path)
+			cfg := ErrorsCfg{Level1text: "default text", Number: 5}
+			out := &bytes.Buffer{}
+			params := insconfig.Params{
+				EnvPrefix:        "testprefix",
+				ConfigPathGetter: getter(),
+				Output:           out,
+			}
+
+			insConfigurator := insconfig.New(params)
+			err := insConfigurator.Load(&cfg)
+			require.True(t, errors.Is(err, insconfig.ErrConfigGenerated), err)
+			require.Empty(t, out.String())
+
+			template, err := os.ReadFile(path)
+			require.NoError(t, err)
+			require.Contains(t, string(template), "number: 5 # int\n")
+		})
+
+		t.Run(name+" not requested", func(t *testing.T) {
+			setArgs(t, "--config", "nonexistent.yaml")
+			getter := getter()
+			require.Equal(t, "nonexistent.yaml", getter.GetConfigPath())
+
+			path, ok := getter.(insconfig.GenConfigPathGetter).GetGenConfigPath()
+			require.False(t, ok)
+			require.Empty(t, path)
+		})
+	}
+}
+
+type testDumpConfigGetter struct {
+	testPathGetter
+}
+
+func (g testDumpConfigGetter) GetDumpConfig() bool {
+	return true
+}
+
+func Test_DumpConfig(t *testing.T) {
+	_ = os.Setenv("TESTPREFIX_NUMBER", "7")
+	defer os.Unsetenv("TESTPREFIX_NUMBER")
+
+	type SecretCfg struct {
+		Number   int
+		Password string `insconfigsecret:""`
+		TLS      struct {
+			Key string
+		} `insconfigsecret:""`
+		Tokens map[string]string `insconfigsecret:""`
+	}
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("number: 5\npassword: secret\ntls:\n  key: secret key\ntokens:\n  api: secret token\n"), 0o600))
+
+	cfg := SecretCfg{}
+	out := &bytes.Buffer{}
+	params := insconfig.Params{
+		EnvPrefix:        "testprefix",
+		ConfigPathGetter: testDumpConfigGetter{testPathGetter{path}},
+		Output:           out,
+	}
+	insConfigurator := insconfig.New(params)
+	err := insConfigurator.Load(&cfg)
+	require.True(t, errors.Is(err, insconfig.ErrConfigDumped), err)
+	require.Equal(t, 7, cfg.Number)
+
+	require.Contains(t, out.String(), "number: 7 # from: env TESTPREFIX_NUMBER\n")
+	require.Contains(t, out.String(), `password: "*****" # from: file `)
+	require.Contains(t, out.String(), "tls: \"*****\"\n")
+	require.Contains(t, out.String(), "tokens: \"*****\"\n")
+	require.NotContains(t, out.String(), "secret")
+}
+
+func Test_DumpConfigFlag(t *testing.T) {
+	for name, getter := range pathGetters() {
+		t.Run(name, func(t *testing.T) {
+			setArgs(t, "--config", "testdata/test_config_defaults.yaml", "--dump-config")
+			cfg := DefaultsCfg{}
+			out := &bytes.Buffer{}
+			params := insconfig.Params{
+				EnvPrefix:        "testprefix",
+				ConfigPathGetter: getter(),
+				Output:           out,
+			}
+
+			insConfigurator := insconfig.New(params)
+			err := insConfigurator.Load(&cfg)
+			require.True(t, errors.Is(err, insconfig.ErrConfigDumped), err)
+			require.Equal(t, 5, cfg.Count)
+			require.Contains(t, out.String(), "count: 5 # from: file testdata/test_config_defaults.yaml:3:8\n")
+		})
+
+		t.Run(name+" not requested", func(t *testing.T) {
+			setArgs(t, "--config", "testdata/test_config_defaults.yaml")
+			cfg := DefaultsCfg{}
+			out := &bytes.Buffer{}
+			params := insconfig.Params{
+				EnvPrefix:        "testprefix",
+				ConfigPathGetter: getter(),
+				Output:           out,
+			}
+
+			insConfigurator := insconfig.New(params)
+			require.NoError(t, insConfigurator.Load(&cfg))
+			require.Empty(t, out.String())
+		})
+	}
+}
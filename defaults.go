@@ -0,0 +1,129 @@
+package insconfig
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Default values and optional fields
+// the first part of insconfig tag sets a default value of the field, such field may be omitted in config files and ENV:
+//     LogLevel   string        `insconfig:"info|Log level"`
+//     MinTimeout time.Duration `insconfig:"10s|Minimal timeout"`
+// Fields without default (or with an empty one, e.g. insconfig:"|comment") are mandatory.
+// Defaults of map value fields apply to every map entry, defaults of struct fields are ignored.
+// insconfigoptional tag marks a field (with all nested fields) that may be absent. An absent optional field
+// is left zero apart from nested defaults, an optional pointer to struct stays nil. An optional block is all or nothing,
+// if any of its nested keys is set, the other ones are checked as usual:
+//     TLS *TLSConfig `insconfigoptional:""`
+
+const optionalTag = "insconfigoptional"
+
+// missingKeysRules describes keys that may be absent in config files and ENV
+type missingKeysRules struct {
+	// defaults of the keys, map values have placeholder keys, e.g. "map.<-key->.field"
+	defaults map[string]string
+	// optional keys, nested keys of them are optional too while none of them is set
+	optional []string
+}
+
+// satisfied returns true if key may be absent, its default is returned if there is one.
+// setKeys are the keys set in config files and ENV
+func (r missingKeysRules) satisfied(key string, setKeys []string) (string, bool, bool) {
+	if value, ok := r.defaults[key]; ok {
+		return value, true, true
+	}
+	for pattern, value := range r.defaults {
+		if matchKeyPattern(pattern, key, false) {
+			return value, true, true
+		}
+	}
+	for _, pattern := range r.optional {
+		if matchKeyPattern(pattern, key, true) && !blockIsSet(pattern, key, setKeys) {
+			return "", false, true
+		}
+	}
+	return "", false, false
+}
+
+// blockIsSet checks whether any key of the optional block matching pattern is set, the block is a prefix of key
+func blockIsSet(pattern, key string, setKeys []string) bool {
+	parts := strings.Split(key, ".")
+	block := strings.Join(parts[:len(strings.Split(pattern, "."))], ".")
+	for _, setKey := range setKeys {
+		if setKey == block || strings.HasPrefix(setKey, block+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// tagDefault returns the default value of the field from insconfig tag
+func tagDefault(field reflect.StructField) (string, bool) {
+	tag, ok := field.Tag.Lookup("insconfig")
+	if !ok {
+		return "", false
+	}
+	parts := strings.SplitN(tag, "|", 2)
+	if len(parts) < 2 || parts[0] == "" {
+		return "", false
+	}
+	return parts[0], true
+}
+
+// tagRules returns missing keys rules of all the fields, keys are built the same way as in deepFieldNames
+func tagRules(configStruct interface{}) missingKeysRules {
+	rules := missingKeysRules{defaults: make(map[string]string)}
+	rules.collect(reflect.TypeOf(configStruct), "")
+	return rules
+}
+
+func (r *missingKeysRules) collect(t reflect.Type, prefix string) {
+	if t == nil {
+		return
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			key, ok := fieldKey(prefix, field)
+			if !ok {
+				continue
+			}
+			if _, ok := field.Tag.Lookup(optionalTag); ok {
+				r.optional = append(r.optional, key)
+			}
+
+			fieldType := field.Type
+			for fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() != reflect.Struct {
+				if value, ok := tagDefault(field); ok {
+					r.defaults[key] = value
+				}
+			}
+			r.collect(field.Type, key)
+		}
+	case reflect.Map:
+		r.collect(t.Elem(), joinKey(prefix, placeholder))
+	}
+}
+
+// matchKeyPattern checks if key matches pattern with placeholders, or starts with it if prefix is set
+func matchKeyPattern(pattern, key string, prefix bool) bool {
+	patternParts := strings.Split(pattern, ".")
+	keyParts := strings.Split(key, ".")
+	if len(keyParts) < len(patternParts) || !prefix && len(keyParts) != len(patternParts) {
+		return false
+	}
+	for n, part := range patternParts {
+		if part != placeholder && part != keyParts[n] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,205 @@
+package insconfig_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/soverenio/insconfig"
+)
+
+type DefaultsValue struct {
+	Name string
+	Port int `insconfig:"8080|Port of the entry"`
+}
+
+type DefaultsCfg struct {
+	Level1text string
+	Timeout    time.Duration `insconfig:"10s|Timeout"`
+	Count      int           `insconfig:"3|Count"`
+	Mandatory  string        `insconfig:"|Mandatory value"`
+	Map        map[string]DefaultsValue
+}
+
+func Test_Defaults(t *testing.T) {
+	t.Run("happy", func(t *testing.T) {
+		cfg := DefaultsCfg{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{"testdata/test_config_defaults.yaml"},
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		require.NoError(t, err)
+		require.Equal(t, 10*time.Second, cfg.Timeout)
+		require.Equal(t, 5, cfg.Count)
+		require.Equal(t, DefaultsValue{Name: "a", Port: 8080}, cfg.Map["a"])
+
+		sources := insConfigurator.Provenance()
+		require.Equal(t, insconfig.Source{Kind: insconfig.SourceDefault}, sources["timeout"])
+		require.Equal(t, insconfig.Source{Kind: insconfig.SourceDefault}, sources["map.a.port"])
+		require.Equal(t, insconfig.SourceFile, sources["count"].Kind)
+	})
+
+	t.Run("env overrides default", func(t *testing.T) {
+		_ = os.Setenv("TESTPREFIX_TIMEOUT", "1m")
+		defer os.Unsetenv("TESTPREFIX_TIMEOUT")
+
+		cfg := DefaultsCfg{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{"testdata/test_config_defaults.yaml"},
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		require.NoError(t, err)
+		require.Equal(t, time.Minute, cfg.Timeout)
+	})
+
+	t.Run("map entry from env", func(t *testing.T) {
+		_ = os.Setenv("TESTPREFIX_LEVEL1TEXT", "text")
+		defer os.Unsetenv("TESTPREFIX_LEVEL1TEXT")
+		_ = os.Setenv("TESTPREFIX_MANDATORY", "value")
+		defer os.Unsetenv("TESTPREFIX_MANDATORY")
+		_ = os.Setenv("TESTPREFIX_MAP_B_NAME", "b")
+		defer os.Unsetenv("TESTPREFIX_MAP_B_NAME")
+
+		cfg := DefaultsCfg{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{""},
+			FileNotRequired:  true,
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		require.NoError(t, err)
+		require.Equal(t, DefaultsValue{Name: "b", Port: 8080}, cfg.Map["b"])
+		require.Equal(t, 3, cfg.Count)
+	})
+
+	t.Run("fail no value without default", func(t *testing.T) {
+		_ = os.Setenv("TESTPREFIX_LEVEL1TEXT", "text")
+		defer os.Unsetenv("TESTPREFIX_LEVEL1TEXT")
+		_ = os.Setenv("TESTPREFIX_MAP_A_NAME", "a")
+		defer os.Unsetenv("TESTPREFIX_MAP_A_NAME")
+
+		cfg := DefaultsCfg{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{""},
+			FileNotRequired:  true,
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		var missingErr *insconfig.MissingKeysError
+		require.True(t, errors.As(err, &missingErr), err)
+		require.Equal(t, []string{"mandatory"}, missingErr.Keys)
+	})
+}
+
+type TLSCfg struct {
+	Cert string
+	Key  string
+}
+
+type OptionalCfg struct {
+	Level1text string
+	TLS        *TLSCfg `insconfigoptional:""`
+	Limits     struct {
+		Max     int
+		Timeout time.Duration `insconfig:"5s|Timeout"`
+	} `insconfigoptional:""`
+}
+
+func Test_Optional(t *testing.T) {
+	t.Run("absent", func(t *testing.T) {
+		_ = os.Setenv("TESTPREFIX_LEVEL1TEXT", "text")
+		defer os.Unsetenv("TESTPREFIX_LEVEL1TEXT")
+
+		cfg := OptionalCfg{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{""},
+			FileNotRequired:  true,
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		require.NoError(t, err)
+		require.Nil(t, cfg.TLS)
+		require.Equal(t, 0, cfg.Limits.Max)
+		require.Equal(t, 5*time.Second, cfg.Limits.Timeout)
+	})
+
+	t.Run("present", func(t *testing.T) {
+		cfg := OptionalCfg{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{"testdata/test_config_optional.yaml"},
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		require.NoError(t, err)
+		require.Equal(t, &TLSCfg{Cert: "cert.pem", Key: "key.pem"}, cfg.TLS)
+	})
+
+	t.Run("block set in ENV only", func(t *testing.T) {
+		_ = os.Setenv("TESTPREFIX_TLS_CERT", "cert.pem")
+		_ = os.Setenv("TESTPREFIX_TLS_KEY", "key.pem")
+		defer os.Unsetenv("TESTPREFIX_TLS_CERT")
+		defer os.Unsetenv("TESTPREFIX_TLS_KEY")
+
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("level1text: text\n"), 0o600))
+		cfg := OptionalCfg{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{path},
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		require.NoError(t, err)
+		require.Equal(t, &TLSCfg{Cert: "cert.pem", Key: "key.pem"}, cfg.TLS)
+	})
+
+	t.Run("fail partially set block", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("level1text: text\ntls:\n  cert: /c.pem\n"), 0o600))
+		cfg := OptionalCfg{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{path},
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		var missingErr *insconfig.MissingKeysError
+		require.True(t, errors.As(err, &missingErr), err)
+		require.Equal(t, []string{"tls.key"}, missingErr.Keys)
+	})
+
+	t.Run("fail mandatory field is still checked", func(t *testing.T) {
+		cfg := OptionalCfg{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{""},
+			FileNotRequired:  true,
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		var missingErr *insconfig.MissingKeysError
+		require.True(t, errors.As(err, &missingErr), err)
+		require.Equal(t, []string{"level1text"}, missingErr.Keys)
+	})
+}
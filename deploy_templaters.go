@@ -0,0 +1,272 @@
+package insconfig
+
+import (
+	"encoding"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Deployment files generation from struct part
+// Every ENV variable of the config gets a value from the provided obj, zero values are replaced by defaults
+// from insconfig tag. Map entries and elements of slices of structs are listed one by one with map keys and indices,
+// an empty map or slice of structs gets commented out placeholder lines. Other slices are comma separated
+
+// envValue is an ENV variable with its value
+type envValue struct {
+	EnvVarInfo
+	Value string
+
+	// collection is the type of map or slice the value is an element of
+	collection reflect.Type
+}
+
+// envValues lists ENV variables of obj with values in the order of struct fields,
+// entries of maps and slices of structs are listed once with placeholders if patterns is set
+func envValues(params Params, obj interface{}, patterns bool) ([]envValue, error) {
+	if params.EnvPrefix == "" {
+		return nil, errors.New("EnvPrefix should be defined")
+	}
+	i := &insConfigurator{params: params}
+	var values []envValue
+	if err := i.collectEnvValues(reflect.ValueOf(obj), "", envValue{}, patterns, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func (i *insConfigurator) collectEnvValues(v reflect.Value, prefix string, parent envValue, patterns bool, values *[]envValue) error {
+	for v.IsValid() && v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v = reflect.Zero(v.Type().Elem())
+			continue
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+
+	switch {
+	case isEnvLeaf(v.Type()):
+	case v.Kind() == reflect.Struct:
+		for _, f := range visibleFields(v.Type(), v) {
+			value := f.value
+			if !value.IsValid() {
+				value = reflect.Zero(f.field.Type)
+			}
+			info := envValue{EnvVarInfo: fieldEnvInfo(f.field, parent.EnvVarInfo), collection: parent.collection}
+			if err := i.collectEnvValues(value, joinKey(prefix, f.naming.Name), info, patterns, values); err != nil {
+				return err
+			}
+		}
+		return nil
+	case v.Kind() == reflect.Map:
+		if keyType := v.Type().Key(); !isSupportedMapKey(keyType) {
+			return errors.New(fmt.Sprintf("maps in config must have string, integer or encoding.TextUnmarshaler keys but got: %s key in %s", keyType.Kind(), v.Type()))
+		}
+		if v.Len() == 0 || patterns {
+			parent.collection = v.Type()
+			return i.collectEnvValues(reflect.Zero(v.Type().Elem()), joinKey(prefix, placeholder), parent, patterns, values)
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(a, b int) bool {
+			return mapKeyString(keys[a]) < mapKeyString(keys[b])
+		})
+		for _, k := range keys {
+			if err := i.collectEnvValues(v.MapIndex(k), joinKey(prefix, mapKeyString(k)), parent, patterns, values); err != nil {
+				return err
+			}
+		}
+		return nil
+	case (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && len(elementKeys(v.Type().Elem(), "")) > 0:
+		if v.Len() == 0 || patterns {
+			parent.collection = v.Type()
+			return i.collectEnvValues(reflect.Zero(v.Type().Elem()), joinKey(prefix, placeholder), parent, patterns, values)
+		}
+		for n := 0; n < v.Len(); n++ {
+			if err := i.collectEnvValues(v.Index(n), joinKey(prefix, strconv.Itoa(n)), parent, patterns, values); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if prefix == "" {
+		return nil
+	}
+	res := parent
+	res.Key = prefix
+	res.Name = i.envName(prefix)
+	res.Type = v.Type().String()
+	res.Pattern = strings.Contains(prefix, placeholder)
+	res.Value = envValueString(v)
+	if v.IsZero() && res.Default != "" {
+		res.Value = res.Default
+	}
+	*values = append(*values, res)
+	return nil
+}
+
+// isEnvLeaf checks whether the type is set by a single ENV value
+func isEnvLeaf(t reflect.Type) bool {
+	if t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		return false
+	}
+	return true
+}
+
+// envValueString formats the value the way Load decodes it from ENV
+func envValueString(v reflect.Value) string {
+	if marshaler, ok := v.Interface().(encoding.TextMarshaler); ok {
+		if text, err := marshaler.MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		parts := make([]string, 0, v.Len())
+		for n := 0; n < v.Len(); n++ {
+			parts = append(parts, envValueString(v.Index(n)))
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// EnvFileTemplater writes a .env file with every ENV variable of the config
+type EnvFileTemplater struct {
+	Params Params      // EnvPrefix and EnvSeparator are used
+	Obj    interface{} // values of the variables
+}
+
+func NewEnvFileTemplater(params Params, obj interface{}) EnvFileTemplater {
+	return EnvFileTemplater{Params: params, Obj: obj}
+}
+
+func (m EnvFileTemplater) TemplateTo(w io.Writer) error {
+	values, err := envValues(m.Params, m.Obj, false)
+	if err != nil {
+		return err
+	}
+	b := &strings.Builder{}
+	for _, v := range values {
+		writeEnvComment(b, "", v)
+		line := fmt.Sprintf("%s=%s", v.Name, envFileQuote(v.Value))
+		if v.Pattern {
+			line = "# " + line
+		}
+		fmt.Fprintln(b, line)
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+func envFileQuote(value string) string {
+	if strings.ContainsAny(value, " \t\n#\"'$\\`") {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+// writeEnvComment writes comment of the variable and a hint for map placeholders
+func writeEnvComment(b *strings.Builder, indent string, v envValue) {
+	if v.Comment != "" {
+		fmt.Fprintf(b, "%s# %s\n", indent, v.Comment)
+	}
+	if v.Pattern {
+		fmt.Fprintf(b, "%s# replace %s by map keys or slice indices\n", indent, strings.ToUpper(placeholder))
+	}
+}
+
+// writeYamlEnvMap writes variables as yaml map, values are quoted so they stay strings
+func writeYamlEnvMap(b *strings.Builder, indent, name string, values []envValue) {
+	if len(values) == 0 {
+		fmt.Fprintf(b, "%s%s: {}\n", indent, name)
+		return
+	}
+	fmt.Fprintf(b, "%s%s:\n", indent, name)
+	indent += "  "
+	for _, v := range values {
+		writeEnvComment(b, indent, v)
+		line := fmt.Sprintf("%s: %s", v.Name, strconv.Quote(v.Value))
+		if v.Pattern {
+			line = "# " + line
+		}
+		fmt.Fprintf(b, "%s%s\n", indent, line)
+	}
+}
+
+// splitSecrets separates values of insconfigsecret fields
+func splitSecrets(values []envValue) ([]envValue, []envValue) {
+	var plain, secret []envValue
+	for _, v := range values {
+		if v.Secret {
+			secret = append(secret, v)
+		} else {
+			plain = append(plain, v)
+		}
+	}
+	return plain, secret
+}
+
+// ConfigMapTemplater writes Kubernetes ConfigMap and Secret manifests with ENV variables of the config,
+// values of insconfigsecret fields go to the Secret
+type ConfigMapTemplater struct {
+	Params Params      // EnvPrefix and EnvSeparator are used
+	Name   string      // name of the ConfigMap and the Secret
+	Obj    interface{} // values of the variables
+}
+
+func NewConfigMapTemplater(params Params, name string, obj interface{}) ConfigMapTemplater {
+	return ConfigMapTemplater{Params: params, Name: name, Obj: obj}
+}
+
+func (m ConfigMapTemplater) TemplateTo(w io.Writer) error {
+	values, err := envValues(m.Params, m.Obj, false)
+	if err != nil {
+		return err
+	}
+	plain, secret := splitSecrets(values)
+
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: %s\n", m.Name)
+	writeYamlEnvMap(b, "", "data", plain)
+	fmt.Fprintf(b, "---\napiVersion: v1\nkind: Secret\nmetadata:\n  name: %s\ntype: Opaque\n", m.Name)
+	writeYamlEnvMap(b, "", "stringData", secret)
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// HelmValuesTemplater writes a skeleton of Helm values.yaml with ENV variables of the config,
+// values of insconfigsecret fields go to secretEnv
+type HelmValuesTemplater struct {
+	Params Params      // EnvPrefix and EnvSeparator are used
+	Obj    interface{} // values of the variables
+}
+
+func NewHelmValuesTemplater(params Params, obj interface{}) HelmValuesTemplater {
+	return HelmValuesTemplater{Params: params, Obj: obj}
+}
+
+func (m HelmValuesTemplater) TemplateTo(w io.Writer) error {
+	values, err := envValues(m.Params, m.Obj, false)
+	if err != nil {
+		return err
+	}
+	plain, secret := splitSecrets(values)
+
+	b := &strings.Builder{}
+	writeYamlEnvMap(b, "", "env", plain)
+	writeYamlEnvMap(b, "", "secretEnv", secret)
+	_, err = io.WriteString(w, b.String())
+	return err
+}
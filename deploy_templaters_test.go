@@ -0,0 +1,136 @@
+package insconfig_test
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/soverenio/insconfig"
+)
+
+type DeployClient struct {
+	Address string
+}
+
+type DeployCfg struct {
+	Timeout  time.Duration `insconfig:"10s|Request timeout"`
+	Name     string        `insconfig:"|Service name"`
+	Password string        `insconfigsecret:""`
+	Peers    []string
+	Clients  []DeployClient
+	Routes   map[string]string `insconfig:"Routes"`
+}
+
+func deployCfg() DeployCfg {
+	return DeployCfg{
+		Name:     "my service",
+		Password: "secret",
+		Peers:    []string{"host1:80", "host2:80"},
+		Clients:  []DeployClient{{Address: "client:8080"}},
+	}
+}
+
+func Test_DeployTemplaters(t *testing.T) {
+	params := insconfig.Params{EnvPrefix: "example"}
+
+	t.Run("env file", func(t *testing.T) {
+		w := &bytes.Buffer{}
+		require.NoError(t, insconfig.NewEnvFileTemplater(params, deployCfg()).TemplateTo(w))
+		require.Equal(t, `# Request timeout
+EXAMPLE_TIMEOUT=10s
+# Service name
+EXAMPLE_NAME="my service"
+EXAMPLE_PASSWORD=secret
+EXAMPLE_PEERS=host1:80,host2:80
+EXAMPLE_CLIENTS_0_ADDRESS=client:8080
+# Routes
+# replace <-KEY-> by map keys or slice indices
+# EXAMPLE_ROUTES_<-KEY->=
+`, w.String())
+	})
+
+	t.Run("config map", func(t *testing.T) {
+		w := &bytes.Buffer{}
+		require.NoError(t, insconfig.NewConfigMapTemplater(params, "my-service", deployCfg()).TemplateTo(w))
+		require.Equal(t, `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: my-service
+data:
+  # Request timeout
+  EXAMPLE_TIMEOUT: "10s"
+  # Service name
+  EXAMPLE_NAME: "my service"
+  EXAMPLE_PEERS: "host1:80,host2:80"
+  EXAMPLE_CLIENTS_0_ADDRESS: "client:8080"
+  # Routes
+  # replace <-KEY-> by map keys or slice indices
+  # EXAMPLE_ROUTES_<-KEY->: ""
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: my-service
+type: Opaque
+stringData:
+  EXAMPLE_PASSWORD: "secret"
+`, w.String())
+	})
+
+	t.Run("helm values", func(t *testing.T) {
+		cfg := deployCfg()
+		cfg.Routes = map[string]string{"eu": "eu:80"}
+		w := &bytes.Buffer{}
+		require.NoError(t, insconfig.NewHelmValuesTemplater(params, &cfg).TemplateTo(w))
+		require.Equal(t, `env:
+  # Request timeout
+  EXAMPLE_TIMEOUT: "10s"
+  # Service name
+  EXAMPLE_NAME: "my service"
+  EXAMPLE_PEERS: "host1:80,host2:80"
+  EXAMPLE_CLIENTS_0_ADDRESS: "client:8080"
+  # Routes
+  EXAMPLE_ROUTES_EU: "eu:80"
+secretEnv:
+  EXAMPLE_PASSWORD: "secret"
+`, w.String())
+	})
+
+	t.Run("fail no prefix", func(t *testing.T) {
+		w := &bytes.Buffer{}
+		require.Error(t, insconfig.NewEnvFileTemplater(insconfig.Params{}, deployCfg()).TemplateTo(w))
+	})
+}
+
+func Test_EnvFileRoundTrip(t *testing.T) {
+	w := &bytes.Buffer{}
+	params := insconfig.Params{EnvPrefix: "testprefix"}
+	require.NoError(t, insconfig.NewEnvFileTemplater(params, OptionalCfg{Level1text: "text"}).TemplateTo(w))
+	require.Contains(t, w.String(), "TESTPREFIX_TLS_CERT=\n")
+
+	for _, line := range strings.Split(w.String(), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		value := kv[1]
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		}
+		_ = os.Setenv(kv[0], value)
+		defer os.Unsetenv(kv[0])
+	}
+
+	cfg := OptionalCfg{}
+	params.ConfigPathGetter = testPathGetter{""}
+	params.FileNotRequired = true
+	insConfigurator := insconfig.New(params)
+	require.NoError(t, insConfigurator.Load(&cfg))
+	require.Equal(t, "text", cfg.Level1text)
+	require.Equal(t, 5*time.Second, cfg.Limits.Timeout)
+}
@@ -0,0 +1,116 @@
+package insconfig
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Reference documentation generation from struct part
+// Every config value is described by its key, ENV variable, type, default, comment from insconfig tag and notes.
+// Defaults are taken from the provided obj, zero values are replaced by defaults from insconfig tag.
+// Entries of maps and slices of structs are described once with placeholders, secret values are hidden
+
+const docsSecretValue = "*****"
+
+// DocsTemplater writes a Markdown or HTML table describing config values
+type DocsTemplater struct {
+	Params Params      // EnvPrefix and EnvSeparator are used
+	Obj    interface{} // defaults of the values
+	HTML   bool        // write HTML instead of Markdown
+}
+
+func NewMarkdownDocsTemplater(params Params, obj interface{}) DocsTemplater {
+	return DocsTemplater{Params: params, Obj: obj}
+}
+
+func NewHTMLDocsTemplater(params Params, obj interface{}) DocsTemplater {
+	return DocsTemplater{Params: params, Obj: obj, HTML: true}
+}
+
+var docsHeader = []string{"Key", "ENV variable", "Type", "Default", "Description", "Notes"}
+
+func (m DocsTemplater) TemplateTo(w io.Writer) error {
+	values, err := envValues(m.Params, m.Obj, true)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(values))
+	for _, v := range values {
+		value := v.Value
+		if v.Secret && value != "" {
+			value = docsSecretValue
+		}
+		rows = append(rows, []string{v.Key, v.Name, v.Type, value, v.Comment, docsNotes(v)})
+	}
+
+	b := &strings.Builder{}
+	if m.HTML {
+		writeHTMLTable(b, rows)
+	} else {
+		writeMarkdownTable(b, rows)
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+func docsNotes(v envValue) string {
+	var notes []string
+	if v.Secret {
+		notes = append(notes, "secret")
+	}
+	if v.Optional {
+		notes = append(notes, "optional")
+	}
+	if v.Default == "" && !v.Optional {
+		notes = append(notes, "required")
+	}
+	switch {
+	case v.collection == nil:
+	case v.collection.Kind() == reflect.Map:
+		notes = append(notes, fmt.Sprintf("%s is a key of %s", strings.ToUpper(placeholder), v.collection))
+	default:
+		notes = append(notes, fmt.Sprintf("%s is an index of %s", strings.ToUpper(placeholder), v.collection))
+	}
+	return strings.Join(notes, ", ")
+}
+
+// writeMarkdownTable writes keys, names and types as code, empty cells stay empty
+func writeMarkdownTable(b *strings.Builder, rows [][]string) {
+	fmt.Fprintf(b, "| %s |\n", strings.Join(docsHeader, " | "))
+	fmt.Fprintf(b, "|%s\n", strings.Repeat("---|", len(docsHeader)))
+	for _, row := range rows {
+		cells := make([]string, 0, len(row))
+		for n, cell := range row {
+			cell = strings.ReplaceAll(strings.ReplaceAll(cell, "|", `\|`), "\n", " ")
+			if cell != "" && n < 4 {
+				cell = "`" + cell + "`"
+			}
+			cells = append(cells, cell)
+		}
+		fmt.Fprintf(b, "| %s |\n", strings.Join(cells, " | "))
+	}
+}
+
+func writeHTMLTable(b *strings.Builder, rows [][]string) {
+	b.WriteString("<table>\n  <tr>")
+	for _, cell := range docsHeader {
+		fmt.Fprintf(b, "<th>%s</th>", cell)
+	}
+	b.WriteString("</tr>\n")
+	for _, row := range rows {
+		b.WriteString("  <tr>")
+		for n, cell := range row {
+			cell = html.EscapeString(cell)
+			if cell != "" && n < 4 {
+				cell = "<code>" + cell + "</code>"
+			}
+			fmt.Fprintf(b, "<td>%s</td>", cell)
+		}
+		b.WriteString("</tr>\n")
+	}
+	b.WriteString("</table>\n")
+}
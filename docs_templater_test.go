@@ -0,0 +1,50 @@
+package insconfig_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/soverenio/insconfig"
+)
+
+type DocsRoute struct {
+	Address string `insconfig:"|Route address"`
+}
+
+type DocsCfg struct {
+	Timeout  time.Duration `insconfig:"10s|Request timeout"`
+	Password string        `insconfigsecret:"" insconfig:"|DB password"`
+	Peers    []string      `insconfigoptional:""`
+	Routes   map[string]DocsRoute
+}
+
+func Test_DocsTemplater(t *testing.T) {
+	cfg := DocsCfg{
+		Password: "secret",
+		Peers:    []string{"host1:80", "host2:80"},
+		Routes:   map[string]DocsRoute{"eu": {Address: "eu:80"}},
+	}
+	params := insconfig.Params{EnvPrefix: "example"}
+
+	t.Run("markdown", func(t *testing.T) {
+		w := &bytes.Buffer{}
+		require.NoError(t, insconfig.NewMarkdownDocsTemplater(params, cfg).TemplateTo(w))
+		require.Equal(t, "| Key | ENV variable | Type | Default | Description | Notes |\n"+
+			"|---|---|---|---|---|---|\n"+
+			"| `timeout` | `EXAMPLE_TIMEOUT` | `time.Duration` | `10s` | Request timeout |  |\n"+
+			"| `password` | `EXAMPLE_PASSWORD` | `string` | `*****` | DB password | secret, required |\n"+
+			"| `peers` | `EXAMPLE_PEERS` | `[]string` | `host1:80,host2:80` |  | optional |\n"+
+			"| `routes.<-key->.address` | `EXAMPLE_ROUTES_<-KEY->_ADDRESS` | `string` |  | Route address | required, <-KEY-> is a key of map[string]insconfig_test.DocsRoute |\n",
+			w.String())
+	})
+
+	t.Run("html", func(t *testing.T) {
+		w := &bytes.Buffer{}
+		require.NoError(t, insconfig.NewHTMLDocsTemplater(params, cfg).TemplateTo(w))
+		require.Contains(t, w.String(), "<tr><th>Key</th><th>ENV variable</th><th>Type</th><th>Default</th><th>Description</th><th>Notes</th></tr>\n")
+		require.Contains(t, w.String(), "<tr><td><code>routes.&lt;-key-&gt;.address</code></td><td><code>EXAMPLE_ROUTES_&lt;-KEY-&gt;_ADDRESS</code></td>")
+	})
+}
@@ -0,0 +1,263 @@
+package insconfig
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ENV overrides of slice elements
+// Besides a whole comma separated value, elements of a slice may be set by indexed ENV variables:
+//     EXAMPLE_PEERS_2=host:port
+//     EXAMPLE_CLIENTS_0_ADDRESS=host:port
+// An index refers to an element loaded from config files or appends a new one, indices must be contiguous.
+// Every field of an appended struct element must be set in ENV unless it has a default or is optional
+
+// sliceEnvValue is a slice element (or a field of it) set by ENV variable
+type sliceEnvValue struct {
+	name  string
+	value string
+	index int
+	// field is the key of the element field, empty for scalar elements
+	field string
+}
+
+func (v sliceEnvValue) key(sliceKey string) string {
+	key := joinKey(sliceKey, strconv.Itoa(v.index))
+	if v.field == "" {
+		return key
+	}
+	return joinKey(key, v.field)
+}
+
+// sliceKeys returns element types of the slice fields among keys
+func sliceKeys(configType reflect.Type, keys []string) map[string]reflect.Type {
+	res := make(map[string]reflect.Type)
+	for _, key := range keys {
+		t := keyType(configType, key)
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t != nil && t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 {
+			res[key] = t.Elem()
+		}
+	}
+	return res
+}
+
+// keyType returns the type of the field with config key, nil if there is no such field
+func keyType(t reflect.Type, key string) reflect.Type {
+	for _, part := range strings.Split(key, ".") {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			var next reflect.Type
+			for _, f := range visibleFields(t, reflect.Value{}) {
+				if f.naming.Key() == part {
+					next = f.field.Type
+					break
+				}
+			}
+			if next == nil {
+				return nil
+			}
+			t = next
+		case reflect.Map:
+			t = t.Elem()
+		default:
+			return nil
+		}
+	}
+	return t
+}
+
+// elementKeys returns keys of the scalar fields of a slice element type, maps of elements can't be set by ENV
+func elementKeys(t reflect.Type, prefix string) []string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		if t.Kind() == reflect.Map || prefix == "" {
+			return nil
+		}
+		return []string{prefix}
+	}
+	var keys []string
+	for _, f := range visibleFields(t, reflect.Value{}) {
+		keys = append(keys, elementKeys(f.field.Type, joinKey(prefix, f.naming.Name))...)
+	}
+	return keys
+}
+
+// matchSliceEnvName finds the slice element set by ENV variable, the longest slice key wins
+func (i *insConfigurator) matchSliceEnvName(slices map[string]reflect.Type, name, value string) (string, sliceEnvValue, bool) {
+	keys := make([]string, 0, len(slices))
+	for k := range slices {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(a, b int) bool {
+		return len(keys[a]) > len(keys[b])
+	})
+
+	for _, key := range keys {
+		separator := strings.ToUpper(i.envSeparator())
+		rest := strings.TrimPrefix(name, i.envName(key)+separator)
+		if rest == name {
+			continue
+		}
+		parts := strings.SplitN(rest, separator, 2)
+		index, err := strconv.Atoi(parts[0])
+		if err != nil || index < 0 || strconv.Itoa(index) != parts[0] {
+			continue
+		}
+		v := sliceEnvValue{name: name, value: value, index: index}
+
+		fields := elementKeys(slices[key], "")
+		if len(fields) == 0 {
+			if len(parts) == 1 {
+				return key, v, true
+			}
+			continue
+		}
+		if len(parts) == 1 {
+			continue
+		}
+		for _, field := range fields {
+			if strings.ToUpper(strings.ReplaceAll(field, ".", separator)) == parts[1] {
+				v.field = field
+				return key, v, true
+			}
+		}
+	}
+	return "", sliceEnvValue{}, false
+}
+
+// setSliceEnvValues sets elements of the slice, values with non contiguous indices are returned as invalid
+// together with the fields of appended elements missing in ENV
+func (i *insConfigurator) setSliceEnvValues(key string, elemType reflect.Type, values []sliceEnvValue) ([]sliceEnvValue, []string) {
+	sort.Slice(values, func(a, b int) bool {
+		if values[a].index != values[b].index {
+			return values[a].index < values[b].index
+		}
+		return values[a].field < values[b].field
+	})
+
+	slice := toInterfaceSlice(i.viper.Get(key))
+	fields := elementKeys(elemType, "")
+	var invalid []sliceEnvValue
+	appended := make(map[int]map[string]bool)
+	for _, v := range values {
+		switch {
+		case v.index > len(slice):
+			invalid = append(invalid, v)
+			continue
+		case v.index == len(slice):
+			if len(fields) == 0 {
+				slice = append(slice, nil)
+			} else {
+				slice = append(slice, map[string]interface{}{})
+			}
+			appended[v.index] = make(map[string]bool)
+		}
+
+		if v.field == "" {
+			slice[v.index] = v.value
+		} else {
+			slice[v.index] = setPath(slice[v.index], strings.Split(v.field, "."), v.value)
+		}
+		if set, ok := appended[v.index]; ok {
+			set[v.field] = true
+		}
+		i.sources[v.key(key)] = Source{Kind: SourceEnv, Name: v.name}
+	}
+
+	// fields of appended elements follow the same defaults and optional rules as other config keys
+	rules := missingKeysRules{defaults: make(map[string]string)}
+	rules.collect(elemType, "")
+	var missing []string
+	for index, set := range appended {
+		setFields := make([]string, 0, len(set))
+		for field := range set {
+			setFields = append(setFields, field)
+		}
+		for _, field := range fields {
+			if set[field] {
+				continue
+			}
+			elementKey := sliceEnvValue{index: index, field: field}.key(key)
+			if value, hasDefault, ok := rules.satisfied(field, setFields); ok {
+				if hasDefault {
+					slice[index] = setPath(slice[index], strings.Split(field, "."), value)
+					i.sources[elementKey] = Source{Kind: SourceDefault}
+				}
+				continue
+			}
+			missing = append(missing, elementKey)
+		}
+	}
+	i.viper.Set(key, slice)
+	return invalid, missing
+}
+
+// toInterfaceSlice converts a slice value of viper to []interface{}, a string is split by comma
+// the same way StringToSliceHookFunc does
+func toInterfaceSlice(value interface{}) []interface{} {
+	switch value := value.(type) {
+	case nil:
+		return nil
+	case string:
+		if value == "" {
+			return nil
+		}
+		var res []interface{}
+		for _, s := range strings.Split(value, ",") {
+			res = append(res, s)
+		}
+		return res
+	}
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return []interface{}{value}
+	}
+	res := make([]interface{}, 0, v.Len())
+	for n := 0; n < v.Len(); n++ {
+		res = append(res, v.Index(n).Interface())
+	}
+	return res
+}
+
+// setPath sets value of the nested key in a copy of map data, data may be a map decoded from yaml
+func setPath(data interface{}, path []string, value interface{}) interface{} {
+	res := copyStringMap(data)
+	if len(path) == 1 {
+		if key, ok := findKey(res, path[0]); ok {
+			delete(res, key)
+		}
+		res[path[0]] = value
+		return res
+	}
+	key, ok := findKey(res, path[0])
+	if !ok {
+		key = path[0]
+	}
+	res[key] = setPath(res[key], path[1:], value)
+	return res
+}
+
+// copyStringMap returns a copy of map data with string keys, empty map if data isn't a map
+func copyStringMap(data interface{}) map[string]interface{} {
+	switch data := data.(type) {
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(data))
+		for k, v := range data {
+			res[k] = v
+		}
+		return res
+	case map[interface{}]interface{}:
+		return toStringMap(data)
+	}
+	return make(map[string]interface{})
+}
@@ -0,0 +1,184 @@
+package insconfig_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/soverenio/insconfig"
+)
+
+type SliceClient struct {
+	Address string
+	Timeout time.Duration `yaml:"client_timeout"`
+}
+
+type SlicesCfg struct {
+	Clients []SliceClient
+	Peers   []string
+}
+
+type DefaultsSliceClient struct {
+	Address string
+	Timeout time.Duration `insconfig:"5s|Timeout"`
+	TLS     *TLSCfg       `insconfigoptional:""`
+}
+
+type DefaultsSlicesCfg struct {
+	Clients []DefaultsSliceClient
+}
+
+func Test_SliceEnv(t *testing.T) {
+	t.Run("file only", func(t *testing.T) {
+		cfg := SlicesCfg{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{"testdata/test_config_slices.yaml"},
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		require.NoError(t, err)
+		require.Equal(t, []SliceClient{{Address: "first:8080", Timeout: time.Second}}, cfg.Clients)
+		require.Equal(t, []string{"host1:80", "host2:80"}, cfg.Peers)
+	})
+
+	t.Run("override and append elements", func(t *testing.T) {
+		_ = os.Setenv("TESTPREFIX_CLIENTS_0_ADDRESS", "changed:8080")
+		_ = os.Setenv("TESTPREFIX_CLIENTS_1_ADDRESS", "second:8080")
+		_ = os.Setenv("TESTPREFIX_CLIENTS_1_CLIENT_TIMEOUT", "2s")
+		_ = os.Setenv("TESTPREFIX_PEERS_1", "changed:80")
+		_ = os.Setenv("TESTPREFIX_PEERS_2", "host3:80")
+		defer os.Unsetenv("TESTPREFIX_CLIENTS_0_ADDRESS")
+		defer os.Unsetenv("TESTPREFIX_CLIENTS_1_ADDRESS")
+		defer os.Unsetenv("TESTPREFIX_CLIENTS_1_CLIENT_TIMEOUT")
+		defer os.Unsetenv("TESTPREFIX_PEERS_1")
+		defer os.Unsetenv("TESTPREFIX_PEERS_2")
+
+		cfg := SlicesCfg{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{"testdata/test_config_slices.yaml"},
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		require.NoError(t, err)
+		require.Equal(t, []SliceClient{
+			{Address: "changed:8080", Timeout: time.Second},
+			{Address: "second:8080", Timeout: 2 * time.Second},
+		}, cfg.Clients)
+		require.Equal(t, []string{"host1:80", "changed:80", "host3:80"}, cfg.Peers)
+
+		sources := insConfigurator.Provenance()
+		require.Equal(t, insconfig.Source{Kind: insconfig.SourceEnv, Name: "TESTPREFIX_PEERS_2"}, sources["peers.2"])
+		require.Equal(t, insconfig.Source{Kind: insconfig.SourceEnv, Name: "TESTPREFIX_CLIENTS_1_ADDRESS"}, sources["clients.1.address"])
+
+		w := &bytes.Buffer{}
+		require.NoError(t, insconfig.NewYamlDumperWithSources(cfg, sources).DumpTo(w))
+		require.Contains(t, w.String(), "address: second:8080 # from: env TESTPREFIX_CLIENTS_1_ADDRESS\n")
+		require.Contains(t, w.String(), "host3:80 # from: env TESTPREFIX_PEERS_2\n")
+	})
+
+	t.Run("elements of env only slice", func(t *testing.T) {
+		_ = os.Setenv("TESTPREFIX_CLIENTS_0_ADDRESS", "first:8080")
+		_ = os.Setenv("TESTPREFIX_CLIENTS_0_CLIENT_TIMEOUT", "1s")
+		_ = os.Setenv("TESTPREFIX_PEERS", "host1:80,host2:80")
+		_ = os.Setenv("TESTPREFIX_PEERS_2", "host3:80")
+		defer os.Unsetenv("TESTPREFIX_CLIENTS_0_ADDRESS")
+		defer os.Unsetenv("TESTPREFIX_CLIENTS_0_CLIENT_TIMEOUT")
+		defer os.Unsetenv("TESTPREFIX_PEERS")
+		defer os.Unsetenv("TESTPREFIX_PEERS_2")
+
+		cfg := SlicesCfg{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{""},
+			FileNotRequired:  true,
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		require.NoError(t, err)
+		require.Equal(t, []SliceClient{{Address: "first:8080", Timeout: time.Second}}, cfg.Clients)
+		require.Equal(t, []string{"host1:80", "host2:80", "host3:80"}, cfg.Peers)
+	})
+
+	t.Run("fail index gap", func(t *testing.T) {
+		_ = os.Setenv("TESTPREFIX_PEERS_5", "host:80")
+		_ = os.Setenv("TESTPREFIX_CLIENTS_0_PORT", "80")
+		defer os.Unsetenv("TESTPREFIX_PEERS_5")
+		defer os.Unsetenv("TESTPREFIX_CLIENTS_0_PORT")
+
+		cfg := SlicesCfg{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{"testdata/test_config_slices.yaml"},
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		var envErr *insconfig.UnknownEnvKeysError
+		require.True(t, errors.As(err, &envErr), err)
+		require.Equal(t, []string{"clients.0.port", "peers.5"}, envErr.Keys)
+		require.Equal(t, []string{"TESTPREFIX_CLIENTS_0_PORT", "TESTPREFIX_PEERS_5"}, envErr.Vars)
+	})
+
+	t.Run("fail incomplete new element", func(t *testing.T) {
+		_ = os.Setenv("TESTPREFIX_CLIENTS_1_ADDRESS", "second:8080")
+		defer os.Unsetenv("TESTPREFIX_CLIENTS_1_ADDRESS")
+
+		cfg := SlicesCfg{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{"testdata/test_config_slices.yaml"},
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		var missingErr *insconfig.MissingKeysError
+		require.True(t, errors.As(err, &missingErr), err)
+		require.Equal(t, []string{"clients.1.client_timeout"}, missingErr.Keys)
+	})
+	t.Run("defaults and optional fields of new element", func(t *testing.T) {
+		_ = os.Setenv("TESTPREFIX_CLIENTS_0_ADDRESS", "first:8080")
+		defer os.Unsetenv("TESTPREFIX_CLIENTS_0_ADDRESS")
+
+		cfg := DefaultsSlicesCfg{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{""},
+			FileNotRequired:  true,
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		require.NoError(t, err)
+		require.Equal(t, []DefaultsSliceClient{{Address: "first:8080", Timeout: 5 * time.Second}}, cfg.Clients)
+		require.Equal(t, insconfig.Source{Kind: insconfig.SourceDefault}, insConfigurator.Provenance()["clients.0.timeout"])
+	})
+
+	t.Run("fail partially set optional block of new element", func(t *testing.T) {
+		_ = os.Setenv("TESTPREFIX_CLIENTS_0_ADDRESS", "first:8080")
+		_ = os.Setenv("TESTPREFIX_CLIENTS_0_TLS_CERT", "cert.pem")
+		defer os.Unsetenv("TESTPREFIX_CLIENTS_0_ADDRESS")
+		defer os.Unsetenv("TESTPREFIX_CLIENTS_0_TLS_CERT")
+
+		cfg := DefaultsSlicesCfg{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{""},
+			FileNotRequired:  true,
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		var missingErr *insconfig.MissingKeysError
+		require.True(t, errors.As(err, &missingErr), err)
+		require.Equal(t, []string{"clients.0.tls.key"}, missingErr.Keys)
+	})
+}
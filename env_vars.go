@@ -0,0 +1,59 @@
+package insconfig
+
+import (
+	"reflect"
+	"strings"
+)
+
+// EnvVarInfo describes an ENV variable overriding a config value
+type EnvVarInfo struct {
+	// Name of the variable, map keys and slice indices are placeholders, e.g. "EXAMPLE_ROUTES_<-KEY->_ADDRESS"
+	Name string
+	// Key is the config key, e.g. "routes.<-key->.address"
+	Key string
+	// Type is the Go type of the value, e.g. "time.Duration"
+	Type string
+	// Comment is the comment part of insconfig tag
+	Comment string
+	// Default is the default part of insconfig tag, empty if there is no default
+	Default string
+	// Optional is set if the value may be absent, see insconfigoptional tag
+	Optional bool
+	// Secret is set for values of insconfigsecret fields and their nested fields
+	Secret bool
+	// Pattern is set if Name has placeholders
+	Pattern bool
+}
+
+// EnvVars lists ENV variables accepted by Load for configStruct in the order of struct fields.
+// Keys are built the same way as in Load, entries of maps and slices of structs are listed once with placeholders
+func EnvVars(params Params, configStruct interface{}) ([]EnvVarInfo, error) {
+	values, err := envValues(params, configStruct, true)
+	if err != nil {
+		return nil, err
+	}
+	vars := make([]EnvVarInfo, 0, len(values))
+	for _, v := range values {
+		vars = append(vars, v.EnvVarInfo)
+	}
+	return vars, nil
+}
+
+// fieldEnvInfo returns tag based info of the field, Optional and Secret are inherited from parent
+func fieldEnvInfo(field reflect.StructField, parent EnvVarInfo) EnvVarInfo {
+	info := EnvVarInfo{Optional: parent.Optional, Secret: parent.Secret}
+	if _, ok := field.Tag.Lookup(optionalTag); ok {
+		info.Optional = true
+	}
+	if _, ok := field.Tag.Lookup("insconfigsecret"); ok {
+		info.Secret = true
+	}
+	if tag, ok := field.Tag.Lookup("insconfig"); ok {
+		parts := strings.SplitN(tag, "|", 2)
+		info.Comment = strings.TrimSpace(parts[len(parts)-1])
+	}
+	if value, ok := tagDefault(field); ok {
+		info.Default = value
+	}
+	return info
+}
@@ -0,0 +1,62 @@
+package insconfig_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/soverenio/insconfig"
+)
+
+type EnvVarsRoute struct {
+	Address string `insconfig:"|Route address"`
+	Retries int    `insconfig:"3|Retries"`
+}
+
+type EnvVarsCfg struct {
+	Timeout time.Duration `insconfig:"10s|Request timeout"`
+	DB      struct {
+		Password string `insconfigsecret:"" insconfig:"|DB password"`
+	}
+	TLS *struct {
+		Cert string `mapstructure:"cert_file"`
+	} `insconfigoptional:""`
+	Routes  map[string]EnvVarsRoute
+	Skipped string `mapstructure:"-"`
+	Clients []EnvVarsRoute
+	Peers   []string
+}
+
+func Test_EnvVars(t *testing.T) {
+	t.Run("happy", func(t *testing.T) {
+		vars, err := insconfig.EnvVars(insconfig.Params{EnvPrefix: "example"}, &EnvVarsCfg{})
+		require.NoError(t, err)
+		require.Equal(t, []insconfig.EnvVarInfo{
+			{Name: "EXAMPLE_TIMEOUT", Key: "timeout", Type: "time.Duration", Comment: "Request timeout", Default: "10s"},
+			{Name: "EXAMPLE_DB_PASSWORD", Key: "db.password", Type: "string", Comment: "DB password", Secret: true},
+			{Name: "EXAMPLE_TLS_CERT_FILE", Key: "tls.cert_file", Type: "string", Optional: true},
+			{Name: "EXAMPLE_ROUTES_<-KEY->_ADDRESS", Key: "routes.<-key->.address", Type: "string", Comment: "Route address", Pattern: true},
+			{Name: "EXAMPLE_ROUTES_<-KEY->_RETRIES", Key: "routes.<-key->.retries", Type: "int", Comment: "Retries", Default: "3", Pattern: true},
+			{Name: "EXAMPLE_CLIENTS_<-KEY->_ADDRESS", Key: "clients.<-key->.address", Type: "string", Comment: "Route address", Pattern: true},
+			{Name: "EXAMPLE_CLIENTS_<-KEY->_RETRIES", Key: "clients.<-key->.retries", Type: "int", Comment: "Retries", Default: "3", Pattern: true},
+			{Name: "EXAMPLE_PEERS", Key: "peers", Type: "[]string"},
+		}, vars)
+	})
+
+	t.Run("separator", func(t *testing.T) {
+		vars, err := insconfig.EnvVars(insconfig.Params{EnvPrefix: "example", EnvSeparator: "__"}, &EnvVarsCfg{})
+		require.NoError(t, err)
+		require.Equal(t, "EXAMPLE_TLS__CERT_FILE", vars[2].Name)
+	})
+
+	t.Run("fail no prefix", func(t *testing.T) {
+		_, err := insconfig.EnvVars(insconfig.Params{}, &EnvVarsCfg{})
+		require.EqualError(t, err, "EnvPrefix should be defined")
+	})
+
+	t.Run("fail map key", func(t *testing.T) {
+		_, err := insconfig.EnvVars(insconfig.Params{EnvPrefix: "example"}, &struct{ Map map[float64]string }{})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,267 @@
+package insconfig
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/soverenio/insconfig/utils"
+)
+
+// Errors returned by Load, use errors.As to get the details.
+// With Params.AllErrors Load returns MultiError containing all the problems found
+
+// ErrConfigGenerated is returned by Load when a config template is written instead of loading config,
+// see GenConfigPathGetter. The application is expected to exit
+var ErrConfigGenerated = errors.New("config template is generated")
+
+// ErrConfigDumped is returned by Load when the loaded config is printed, see DumpConfigGetter.
+// The config is loaded successfully, but the application is expected to exit
+var ErrConfigDumped = errors.New("config is dumped")
+
+// MissingKeysError - keys are set neither in config files nor in ENV
+type MissingKeysError struct {
+	Keys []string
+	// Suggestions maps a missing key to a similar unknown key found in config, probably misspelled
+	Suggestions map[string]string
+
+	// keys with descriptions of their incomplete parts defined in config files
+	described []string
+}
+
+func (e *MissingKeysError) Error() string {
+	keys := make([]string, 0, len(e.Keys))
+	for n, key := range e.Keys {
+		described := key
+		if n < len(e.described) {
+			described = e.described[n]
+		}
+		if suggestion := e.Suggestions[key]; suggestion != "" {
+			described = fmt.Sprintf("%s (misspelled as %s?)", described, suggestion)
+		}
+		keys = append(keys, described)
+	}
+	return fmt.Sprintf("Keys is not defined in config: %s", strings.Join(keys, ", "))
+}
+
+// UnknownEnvKeysError - ENV variables with EnvPrefix don't match any config key
+type UnknownEnvKeysError struct {
+	// Keys converted from variable names, e.g. "nonexistent.value"
+	Keys []string
+	// Vars are variable names, e.g. "EXAMPLE_NONEXISTENT_VALUE"
+	Vars []string
+	// Suggestions maps a key to the closest valid key
+	Suggestions map[string]string
+}
+
+func (e *UnknownEnvKeysError) Error() string {
+	keys := make([]string, 0, len(e.Keys))
+	for _, key := range e.Keys {
+		keys = append(keys, annotateKey(key, suggestionNote(e.Suggestions[key])))
+	}
+	return fmt.Sprintf("Wrong config keys found in ENV: %s", strings.Join(keys, ", "))
+}
+
+// AmbiguousEnvKeysError - several config keys have the same ENV name, see Params.EnvSeparator
+type AmbiguousEnvKeysError struct {
+	// Vars are ambiguous variable names, e.g. "EXAMPLE_HOST_MAX_CONN"
+	Vars []string
+	// Keys maps a variable name to its config keys, e.g. "host.max.conn" and "host.max_conn"
+	Keys map[string][]string
+}
+
+func (e *AmbiguousEnvKeysError) Error() string {
+	vars := make([]string, 0, len(e.Vars))
+	for _, name := range e.Vars {
+		vars = append(vars, fmt.Sprintf("%s (%s)", name, strings.Join(e.Keys[name], ", ")))
+	}
+	return fmt.Sprintf("ENV names are ambiguous, set another EnvSeparator: %s", strings.Join(vars, ", "))
+}
+
+// UnknownKeysError - config files contain keys not present in config structure
+type UnknownKeysError struct {
+	Keys []string
+	// Sources maps a key to the places where it's defined
+	Sources map[string][]Source
+	// Suggestions maps a key to the closest valid key
+	Suggestions map[string]string
+}
+
+func (e *UnknownKeysError) Error() string {
+	keys := make([]string, 0, len(e.Keys))
+	for _, key := range e.Keys {
+		keys = append(keys, annotateKey(key, sourcesNote(e.Sources[key]), suggestionNote(e.Suggestions[key])))
+	}
+	return fmt.Sprintf("failed to unmarshal config file into configuration structure: unknown keys: %s", strings.Join(keys, ", "))
+}
+
+// DuplicateKeyError - the same key is defined twice in a config file map
+type DuplicateKeyError struct {
+	File string
+	// Keys are duplicated keys, Sources are places of their repeated definitions
+	Keys    []string
+	Sources []Source
+
+	err error
+}
+
+func (e *DuplicateKeyError) Error() string {
+	msg := fmt.Sprintf("failed to unmarshal config file into configuration structure, file %s: %v", e.File, e.err)
+	if len(e.Keys) == 0 {
+		return msg
+	}
+	keys := make([]string, 0, len(e.Keys))
+	for n, key := range e.Keys {
+		keys = append(keys, annotateKey(key, sourcesNote(e.Sources[n:n+1])))
+	}
+	return fmt.Sprintf("%s, duplicated keys: %s", msg, strings.Join(keys, ", "))
+}
+
+func (e *DuplicateKeyError) Unwrap() error {
+	return e.err
+}
+
+// DecodeError - value can't be decoded into config structure, e.g. type mismatch
+type DecodeError struct {
+	// Key is empty if error is not related to a particular key
+	Key string
+	// Source of the wrong value, empty if it's unknown
+	Source Source
+
+	err error
+}
+
+func (e *DecodeError) Error() string {
+	msg := fmt.Sprintf("failed to unmarshal config file into configuration structure: %v", e.err)
+	if e.Source.Kind == "" {
+		return msg
+	}
+	return fmt.Sprintf("%s (%s)", msg, sourcesNote([]Source{e.Source}))
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.err
+}
+
+// ValidationError - values don't pass insconfigvalidate rules or Validate checks
+type ValidationError struct {
+	Errors []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("Keys failed validation: %s", strings.Join(msgs, "; "))
+}
+
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// FieldError is a single validation failure
+type FieldError struct {
+	// Key of the value, e.g. "clients.a.address"
+	Key string
+	// Path of the value, e.g. ".Clients['a'].Address"
+	Path utils.Path
+	// Rule is a failed insconfigvalidate rule, empty for Validate errors
+	Rule string
+
+	err error
+}
+
+func (e *FieldError) Error() string {
+	if e.Rule != "" {
+		return fmt.Sprintf("%s %v", e.Key, e.err)
+	}
+	return fmt.Sprintf("%s: %v", e.Path, e.err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.err
+}
+
+func sourcesNote(sources []Source) string {
+	if len(sources) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(sources))
+	for _, source := range sources {
+		names = append(names, source.String())
+	}
+	return fmt.Sprintf("defined in %s", strings.Join(names, ", "))
+}
+
+func suggestionNote(suggestion string) string {
+	if suggestion == "" {
+		return ""
+	}
+	return fmt.Sprintf("did you mean %s?", suggestion)
+}
+
+// annotateKey adds non-empty notes in parentheses after key
+func annotateKey(key string, notes ...string) string {
+	var nonEmpty []string
+	for _, note := range notes {
+		if note != "" {
+			nonEmpty = append(nonEmpty, note)
+		}
+	}
+	if len(nonEmpty) == 0 {
+		return key
+	}
+	return fmt.Sprintf("%s (%s)", key, strings.Join(nonEmpty, ", "))
+}
+
+// MultiError contains several problems found in config
+type MultiError struct {
+	Errors []error
+}
+
+func (e *MultiError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e *MultiError) Unwrap() []error {
+	return e.Errors
+}
+
+// errorCollector stops loading on the first error or collects all of them if Params.AllErrors is set
+type errorCollector struct {
+	all  bool
+	errs []error
+}
+
+// add returns true if loading should stop
+func (c *errorCollector) add(err error) bool {
+	if err == nil {
+		return false
+	}
+	c.errs = append(c.errs, err)
+	return !c.all
+}
+
+func (c *errorCollector) result() error {
+	switch len(c.errs) {
+	case 0:
+		return nil
+	case 1:
+		return c.errs[0]
+	}
+	return &MultiError{Errors: c.errs}
+}
+
+// combineErrors returns nil, a single error or MultiError
+func combineErrors(errs []error) error {
+	return (&errorCollector{errs: errs}).result()
+}
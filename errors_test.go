@@ -0,0 +1,209 @@
+package insconfig_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/soverenio/insconfig"
+)
+
+type ErrorsCfg struct {
+	Level1text string
+	Number     int
+	Level2     Level2
+}
+
+func Test_Errors(t *testing.T) {
+	t.Run("first error", func(t *testing.T) {
+		cfg := ErrorsCfg{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{"testdata/test_config_errors.yaml"},
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		require.Error(t, err)
+		var multiErr *insconfig.MultiError
+		require.True(t, errors.As(err, &multiErr))
+		var unknownErr *insconfig.UnknownKeysError
+		require.True(t, errors.As(err, &unknownErr))
+		require.Equal(t, []string{"nonexistent"}, unknownErr.Keys)
+		var decodeErr *insconfig.DecodeError
+		require.True(t, errors.As(err, &decodeErr))
+		require.Equal(t, "number", decodeErr.Key)
+		require.Equal(t, insconfig.Source{Kind: insconfig.SourceFile, Name: "testdata/test_config_errors.yaml", Line: 1, Column: 9}, decodeErr.Source)
+		require.Contains(t, err.Error(), "nonexistent (defined in file testdata/test_config_errors.yaml:4:1)")
+		require.Contains(t, err.Error(), "(defined in file testdata/test_config_errors.yaml:1:9)")
+		// loading stops on decoding
+		var missingErr *insconfig.MissingKeysError
+		require.False(t, errors.As(err, &missingErr))
+	})
+
+	t.Run("all errors", func(t *testing.T) {
+		_ = os.Setenv("TESTPREFIX_WRONGKEY", "value")
+		defer os.Unsetenv("TESTPREFIX_WRONGKEY")
+
+		cfg := ErrorsCfg{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{"testdata/test_config_errors.yaml"},
+			AllErrors:        true,
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		require.Error(t, err)
+		var unknownErr *insconfig.UnknownKeysError
+		require.True(t, errors.As(err, &unknownErr))
+		require.Equal(t, []string{"nonexistent"}, unknownErr.Keys)
+		var decodeErr *insconfig.DecodeError
+		require.True(t, errors.As(err, &decodeErr))
+		require.Equal(t, "number", decodeErr.Key)
+		var envErr *insconfig.UnknownEnvKeysError
+		require.True(t, errors.As(err, &envErr))
+		require.Equal(t, []string{"wrongkey"}, envErr.Keys)
+		require.Equal(t, []string{"TESTPREFIX_WRONGKEY"}, envErr.Vars)
+		var missingErr *insconfig.MissingKeysError
+		require.True(t, errors.As(err, &missingErr))
+		require.ElementsMatch(t, []string{"level1text", "level2.level3.level3text", "level2.level3.nullstring"}, missingErr.Keys)
+	})
+
+	t.Run("wrong type in env", func(t *testing.T) {
+		_ = os.Setenv("TESTPREFIX_NUMBER", "not-a-number")
+		defer os.Unsetenv("TESTPREFIX_NUMBER")
+
+		cfg := ErrorsCfg{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{""},
+			FileNotRequired:  true,
+			AllErrors:        true,
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		var decodeErr *insconfig.DecodeError
+		require.True(t, errors.As(err, &decodeErr), err)
+		require.Equal(t, insconfig.Source{Kind: insconfig.SourceEnv, Name: "TESTPREFIX_NUMBER"}, decodeErr.Source)
+		require.Contains(t, err.Error(), "(defined in env TESTPREFIX_NUMBER)")
+	})
+
+	t.Run("wrong type in slice element", func(t *testing.T) {
+		type Item struct {
+			ID int
+		}
+		type ItemsCfg struct {
+			Items []Item
+		}
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("items:\n  - id: 1\n  - id: wrong\n"), 0o600))
+
+		cfg := ItemsCfg{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{path},
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		var decodeErr *insconfig.DecodeError
+		require.True(t, errors.As(err, &decodeErr), err)
+		require.Equal(t, "items.1.id", decodeErr.Key)
+		require.Equal(t, insconfig.Source{Kind: insconfig.SourceFile, Name: path, Line: 3, Column: 9}, decodeErr.Source)
+		require.Contains(t, err.Error(), fmt.Sprintf("(defined in file %s:3:9)", path))
+	})
+
+	t.Run("duplicated keys", func(t *testing.T) {
+		type MapValue struct {
+			Str  string
+			Num  int
+			Flag bool
+		}
+		type OneMap struct {
+			One map[string]MapValue
+		}
+
+		cfg := OneMap{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{"testdata/test_config_key_duplication.yaml"},
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		var dupErr *insconfig.DuplicateKeyError
+		require.True(t, errors.As(err, &dupErr))
+		require.Equal(t, "testdata/test_config_key_duplication.yaml", dupErr.File)
+		require.Equal(t, []string{"one.first"}, dupErr.Keys)
+		require.Equal(t, []insconfig.Source{{Kind: insconfig.SourceFile, Name: "testdata/test_config_key_duplication.yaml", Line: 6, Column: 3}}, dupErr.Sources)
+		require.Contains(t, err.Error(), "duplicated keys: one.first (defined in file testdata/test_config_key_duplication.yaml:6:3)")
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		cfg := ValidatedCfg{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{"testdata/test_config_validation.yaml"},
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		var validationErr *insconfig.ValidationError
+		require.True(t, errors.As(err, &validationErr))
+		require.NotEmpty(t, validationErr.Errors)
+		for _, fieldErr := range validationErr.Errors {
+			require.NotEmpty(t, fieldErr.Key)
+			require.NotEmpty(t, fieldErr.Rule)
+		}
+	})
+}
+
+func Test_Suggestions(t *testing.T) {
+	t.Run("yaml", func(t *testing.T) {
+		cfg := CfgStruct{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{"testdata/test_config_typo.yaml"},
+			AllErrors:        true,
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		require.Error(t, err)
+		var unknownErr *insconfig.UnknownKeysError
+		require.True(t, errors.As(err, &unknownErr))
+		require.Equal(t, "level1text", unknownErr.Suggestions["level1txt"])
+		require.Equal(t, "mapfield.key1.level2text", unknownErr.Suggestions["mapfield.key1.level2txt"])
+		require.Contains(t, err.Error(), "level1txt (defined in file testdata/test_config_typo.yaml:1:1, did you mean level1text?)")
+		var missingErr *insconfig.MissingKeysError
+		require.True(t, errors.As(err, &missingErr))
+		require.Equal(t, "level1txt", missingErr.Suggestions["level1text"])
+		require.Contains(t, err.Error(), "level1text (misspelled as level1txt?)")
+	})
+
+	t.Run("env", func(t *testing.T) {
+		_ = os.Setenv("TESTPREFIX_LEVEL2_LEVEL2TXT", "value")
+		defer os.Unsetenv("TESTPREFIX_LEVEL2_LEVEL2TXT")
+		_ = os.Setenv("TESTPREFIX_NOTHINGSIMILAR", "value")
+		defer os.Unsetenv("TESTPREFIX_NOTHINGSIMILAR")
+
+		cfg := CfgStruct{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{"testdata/test_config.yaml"},
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		var envErr *insconfig.UnknownEnvKeysError
+		require.True(t, errors.As(err, &envErr))
+		require.Equal(t, map[string]string{"level2.level2txt": "level2.level2text"}, envErr.Suggestions)
+		require.Contains(t, err.Error(), "level2.level2txt (did you mean level2.level2text?)")
+	})
+}
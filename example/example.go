@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 
@@ -41,6 +42,9 @@ func main() {
 	}
 	insConfigurator := insconfig.New(params)
 	if err := insConfigurator.Load(&mconf); err != nil {
+		if errors.Is(err, insconfig.ErrConfigGenerated) || errors.Is(err, insconfig.ErrConfigDumped) {
+			return
+		}
 		panic(err)
 	}
 	fmt.Println(*testflag1)
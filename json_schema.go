@@ -0,0 +1,178 @@
+package insconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// JSON Schema generation from struct part
+// The schema mirrors the strict loader: unknown keys are not allowed, fields without default that aren't
+// marked by insconfigoptional are required. Comments and defaults come from insconfig tag,
+// insconfigsecret fields are writeOnly
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchemaTemplater writes JSON Schema (draft 2020-12) of config files for the type of Obj
+type JSONSchemaTemplater struct {
+	Obj interface{} // only the type of Obj is used
+}
+
+func NewJSONSchemaTemplater(obj interface{}) JSONSchemaTemplater {
+	return JSONSchemaTemplater{Obj: obj}
+}
+
+func (m JSONSchemaTemplater) TemplateTo(w io.Writer) error {
+	schema, err := jsonSchema(reflect.TypeOf(m.Obj))
+	if err != nil {
+		return err
+	}
+	schema["$schema"] = jsonSchemaDraft
+	if properties, ok := schema["properties"].(map[string]interface{}); ok {
+		// include directive is allowed in the root of config files
+		properties[includeKey] = map[string]interface{}{
+			"oneOf": []interface{}{
+				map[string]interface{}{"type": "string"},
+				map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+			},
+		}
+	}
+
+	out, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal JSON Schema")
+	}
+	_, err = fmt.Fprintf(w, "%s\n", out)
+	return err
+}
+
+// jsonSchema returns schema of the type, keys of config files are built the same way as in Load.
+// An insconfigoptional block is all or nothing, so its nested fields are required inside of it
+func jsonSchema(t reflect.Type) (map[string]interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == durationType || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return map[string]interface{}{"type": "string"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		required := make([]string, 0)
+		for _, f := range visibleFields(t, reflect.Value{}) {
+			info := fieldEnvInfo(f.field, EnvVarInfo{})
+			schema, err := jsonSchema(f.field.Type)
+			if err != nil {
+				return nil, err
+			}
+			if info.Comment != "" {
+				schema["description"] = info.Comment
+			}
+			if info.Default != "" {
+				schema["default"] = jsonDefault(info.Default, schema["type"])
+			}
+			if info.Secret {
+				schema["writeOnly"] = true
+			}
+			properties[f.naming.Name] = schema
+
+			if !info.Optional && info.Default == "" && (schema["type"] != "object" || isRequiredObject(schema)) {
+				required = append(required, f.naming.Name)
+			}
+		}
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": false,
+		}, nil
+	case reflect.Map:
+		if keyType := t.Key(); !isSupportedMapKey(keyType) {
+			return nil, errors.New(fmt.Sprintf("maps in config must have string, integer or encoding.TextUnmarshaler keys but got: %s key in %s", keyType.Kind(), t))
+		}
+		elem, err := jsonSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{
+			"type":                 "object",
+			"patternProperties":    map[string]interface{}{mapKeyPattern(t.Key()): elem},
+			"additionalProperties": false,
+		}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string"}, nil
+		}
+		items, err := jsonSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "array", "items": items}, nil
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}, nil
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}, nil
+	case reflect.Interface:
+		return map[string]interface{}{}, nil
+	}
+	return nil, errors.New(fmt.Sprintf("JSON Schema of %s kind is not supported: %s", t.Kind(), t))
+}
+
+// isRequiredObject checks whether an object has required fields, a map is required unless its struct values
+// may be empty
+func isRequiredObject(schema map[string]interface{}) bool {
+	if patterns, ok := schema["patternProperties"].(map[string]interface{}); ok {
+		for _, elem := range patterns {
+			elemSchema, _ := elem.(map[string]interface{})
+			if elemSchema["type"] != "object" {
+				return true
+			}
+			return isRequiredObject(elemSchema)
+		}
+	}
+	required, _ := schema["required"].([]string)
+	return len(required) > 0
+}
+
+// mapKeyPattern returns the pattern of config keys of the map key type
+func mapKeyPattern(t reflect.Type) string {
+	if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return "^.+$"
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "^-?[0-9]+$"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "^[0-9]+$"
+	}
+	return "^.+$"
+}
+
+// jsonDefault converts the default from insconfig tag to a value of the schema type
+func jsonDefault(value string, schemaType interface{}) interface{} {
+	switch schemaType {
+	case "integer":
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
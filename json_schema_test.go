@@ -0,0 +1,158 @@
+package insconfig_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	yaml3 "gopkg.in/yaml.v3"
+
+	"github.com/soverenio/insconfig"
+)
+
+type SchemaRoute struct {
+	Address string `insconfig:"|Route address"`
+	Retries int    `insconfig:"3|Retries"`
+}
+
+type SchemaCfg struct {
+	Timeout  time.Duration `insconfig:"10s|Request timeout"`
+	Password string        `insconfigsecret:""`
+	Peers    []string      `yaml:"peer_list"`
+	TLS      *struct {
+		Cert string
+	} `insconfigoptional:""`
+	Routes map[string]SchemaRoute
+	Ports  map[uint16]bool
+}
+
+func Test_JSONSchema(t *testing.T) {
+	t.Run("happy", func(t *testing.T) {
+		w := &bytes.Buffer{}
+		require.NoError(t, insconfig.NewJSONSchemaTemplater(SchemaCfg{}).TemplateTo(w))
+		require.JSONEq(t, `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["password", "peer_list", "routes", "ports"],
+  "properties": {
+    "$include": {"oneOf": [{"type": "string"}, {"type": "array", "items": {"type": "string"}}]},
+    "timeout": {"type": "string", "description": "Request timeout", "default": "10s"},
+    "password": {"type": "string", "writeOnly": true},
+    "peer_list": {"type": "array", "items": {"type": "string"}},
+    "tls": {
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["cert"],
+      "properties": {"cert": {"type": "string"}}
+    },
+    "routes": {
+      "type": "object",
+      "additionalProperties": false,
+      "patternProperties": {
+        "^.+$": {
+          "type": "object",
+          "additionalProperties": false,
+          "required": ["address"],
+          "properties": {
+            "address": {"type": "string", "description": "Route address"},
+            "retries": {"type": "integer", "description": "Retries", "default": 3}
+          }
+        }
+      }
+    },
+    "ports": {
+      "type": "object",
+      "additionalProperties": false,
+      "patternProperties": {"^[0-9]+$": {"type": "boolean"}}
+    }
+  }
+}`, w.String())
+	})
+
+	t.Run("include", func(t *testing.T) {
+		type IncludeCfg struct {
+			Level1text string
+		}
+		w := &bytes.Buffer{}
+		require.NoError(t, insconfig.NewJSONSchemaTemplater(IncludeCfg{}).TemplateTo(w))
+		var schema map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Bytes(), &schema))
+
+		for file, valid := range map[string]bool{
+			"$include: common.yaml\nlevel1text: text\n":                     true,
+			"$include:\n  - common.yaml\n  - maps.yaml\nlevel1text: text\n": true,
+			"$include: 5\nlevel1text: text\n":                               false,
+			"level1text: text\nnonexistent: value\n":                        false,
+		} {
+			var content interface{}
+			require.NoError(t, yaml3.Unmarshal([]byte(file), &content))
+			require.Equal(t, valid, validateJSONSchema(schema, content), file)
+		}
+	})
+
+	t.Run("fail unsupported type", func(t *testing.T) {
+		w := &bytes.Buffer{}
+		require.Error(t, insconfig.NewJSONSchemaTemplater(struct{ C chan int }{}).TemplateTo(w))
+	})
+}
+
+// validateJSONSchema checks value against the subset of JSON Schema used by JSONSchemaTemplater
+func validateJSONSchema(schema map[string]interface{}, value interface{}) bool {
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		matched := 0
+		for _, option := range oneOf {
+			if validateJSONSchema(option.(map[string]interface{}), value) {
+				matched++
+			}
+		}
+		return matched == 1
+	}
+
+	switch schema["type"] {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "integer":
+		_, ok := value.(int)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, item := range items {
+			if !validateJSONSchema(schema["items"].(map[string]interface{}), item) {
+				return false
+			}
+		}
+		return true
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for _, key := range schema["required"].([]interface{}) {
+			if _, ok := object[key.(string)]; !ok {
+				return false
+			}
+		}
+		for key, v := range object {
+			property, ok := properties[key].(map[string]interface{})
+			if !ok {
+				return false
+			}
+			if !validateJSONSchema(property, v) {
+				return false
+			}
+		}
+		return true
+	}
+	return true
+}
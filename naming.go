@@ -0,0 +1,328 @@
+package insconfig
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/mapstructure"
+	"gopkg.in/yaml.v2"
+)
+
+// Field naming
+// Config keys are built from struct fields the same way for loading, ENV, templates and dumps.
+// Name of a field is the first non-empty of:
+//     mapstructure tag name, e.g. mapstructure:"listen_addr"
+//     yaml tag name,         e.g. yaml:"listen_addr,omitempty"
+//     lowercased field name
+// "-" name skips the field, mapstructure squash and yaml inline options put fields of a struct into its parent,
+// omitempty skips zero values in dumps.
+// Unexported fields are skipped
+
+// fieldNaming describes how a struct field is named in config
+type fieldNaming struct {
+	// Name of the field in config files, templates and dumps
+	Name      string
+	Skip      bool
+	Squash    bool
+	OmitEmpty bool
+
+	// decodeName is the name mapstructure expects, empty if it's the Go field name
+	decodeName string
+	// inline is set for squashed fields unknown to mapstructure
+	inline bool
+}
+
+// Key returns the part of config key, keys are case insensitive
+func (n fieldNaming) Key() string {
+	return strings.ToLower(n.Name)
+}
+
+func namingOf(field reflect.StructField) fieldNaming {
+	if !field.IsExported() {
+		return fieldNaming{Name: field.Name, Skip: true}
+	}
+	return namingFromTag(field.Name, field.Tag)
+}
+
+// namingFromTag is used by templaters knowing field name and tag only
+func namingFromTag(goName string, tag reflect.StructTag) fieldNaming {
+	naming := fieldNaming{Name: strings.ToLower(goName)}
+
+	msName, msOptions := splitTag(tag.Get("mapstructure"))
+	yamlName, yamlOptions := splitTag(tag.Get("yaml"))
+	switch {
+	case msName != "":
+		naming.Name = msName
+		naming.decodeName = msName
+	case yamlName != "":
+		naming.Name = yamlName
+	}
+	naming.Skip = naming.Name == "-"
+
+	for _, option := range msOptions {
+		switch option {
+		case "squash":
+			naming.Squash = true
+		case "omitempty":
+			naming.OmitEmpty = true
+		}
+	}
+	for _, option := range yamlOptions {
+		switch option {
+		case "inline":
+			naming.inline = !naming.Squash
+			naming.Squash = true
+		case "omitempty":
+			naming.OmitEmpty = true
+		}
+	}
+	return naming
+}
+
+func splitTag(tag string) (string, []string) {
+	parts := strings.Split(tag, ",")
+	return parts[0], parts[1:]
+}
+
+// isSquashed checks whether fields of the struct are put into its parent
+func isSquashed(field reflect.StructField) bool {
+	return namingOf(field).Squash
+}
+
+// fieldKey returns config key of the field under prefix, ok is false for skipped fields
+func fieldKey(prefix string, field reflect.StructField) (string, bool) {
+	naming := namingOf(field)
+	if naming.Skip {
+		return "", false
+	}
+	if naming.Squash {
+		return prefix, true
+	}
+	return joinKey(prefix, naming.Name), true
+}
+
+// namedField is a struct field visible in config, fields of squashed structs are listed in place of their parent
+type namedField struct {
+	field  reflect.StructField
+	naming fieldNaming
+	// value is invalid if fields are listed by type or a squashed pointer is nil
+	value reflect.Value
+}
+
+// visibleFields returns config fields of the struct type, v may be invalid
+func visibleFields(t reflect.Type, v reflect.Value) []namedField {
+	var fields []namedField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		naming := namingOf(field)
+		if naming.Skip {
+			continue
+		}
+		var value reflect.Value
+		if v.IsValid() {
+			value = v.Field(i)
+		}
+		if naming.Squash {
+			fieldType, fieldValue := field.Type, value
+			for fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+				if fieldValue.IsValid() {
+					fieldValue = fieldValue.Elem()
+				}
+			}
+			if fieldType.Kind() == reflect.Struct {
+				fields = append(fields, visibleFields(fieldType, fieldValue)...)
+				continue
+			}
+		}
+		fields = append(fields, namedField{field: field, naming: naming, value: value})
+	}
+	return fields
+}
+
+// namingHook renames keys named by yaml tags to the names mapstructure expects and nests keys of yaml inline structs
+func namingHook() mapstructure.DecodeHookFuncValue {
+	return func(from, to reflect.Value) (interface{}, error) {
+		if !to.IsValid() || !from.IsValid() {
+			return from.Interface(), nil
+		}
+		t := to.Type()
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return from.Interface(), nil
+		}
+		switch data := from.Interface().(type) {
+		case map[string]interface{}:
+			return renameKeys(t, data), nil
+		case map[interface{}]interface{}:
+			// elements of slices keep maps decoded by yaml.v2
+			return renameKeys(t, toStringMap(data)), nil
+		}
+		return from.Interface(), nil
+	}
+}
+
+func renameKeys(t reflect.Type, data map[string]interface{}) map[string]interface{} {
+	res := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		res[k] = v
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		naming := namingOf(field)
+		switch {
+		case naming.Skip || naming.decodeName != "":
+			continue
+		case naming.inline:
+			inlineType := field.Type
+			for inlineType.Kind() == reflect.Ptr {
+				inlineType = inlineType.Elem()
+			}
+			if inlineType.Kind() != reflect.Struct {
+				continue
+			}
+			nested := make(map[string]interface{})
+			for _, f := range visibleFields(inlineType, reflect.Value{}) {
+				if key, ok := findKey(res, f.naming.Name); ok {
+					nested[key] = res[key]
+					delete(res, key)
+				}
+			}
+			res[field.Name] = nested
+		case !strings.EqualFold(naming.Name, field.Name):
+			if key, ok := findKey(res, naming.Name); ok {
+				res[field.Name] = res[key]
+				delete(res, key)
+			}
+		}
+	}
+	return res
+}
+
+func findKey(data map[string]interface{}, name string) (string, bool) {
+	for k := range data {
+		if strings.EqualFold(k, name) {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+var mapstructureNamePartRegexp = regexp.MustCompile(`[^.\[\]]+`)
+
+// mapstructureNameToKey converts mapstructure field name like "Map[key].Field" to config key "map.key.field"
+// using field names of the config type
+func mapstructureNameToKey(t reflect.Type, name string) string {
+	var parts []string
+	for _, part := range mapstructureNamePartRegexp.FindAllString(name, -1) {
+		for t != nil && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		key := strings.ToLower(part)
+		switch {
+		case t == nil:
+		case t.Kind() == reflect.Struct:
+			var next reflect.Type
+			for _, f := range visibleFields(t, reflect.Value{}) {
+				decodeName := f.naming.decodeName
+				if decodeName == "" {
+					decodeName = f.field.Name
+				}
+				if strings.EqualFold(decodeName, part) {
+					key, next = f.naming.Key(), f.field.Type
+					break
+				}
+			}
+			t = next
+		case t.Kind() == reflect.Map || t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+			t = t.Elem()
+		default:
+			t = nil
+		}
+		parts = append(parts, key)
+	}
+	return strings.Join(parts, ".")
+}
+
+var (
+	yamlMarshalerType   = reflect.TypeOf((*yaml.Marshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// isSupportedMapKey checks whether map keys of the type may be decoded from config keys:
+// strings, integers and types implementing encoding.TextUnmarshaler
+func isSupportedMapKey(t reflect.Type) bool {
+	if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// mapKeyString returns the config key part of the map key, keys are case insensitive
+func mapKeyString(k reflect.Value) string {
+	if marshaler, ok := k.Interface().(encoding.TextMarshaler); ok {
+		if text, err := marshaler.MarshalText(); err == nil {
+			return strings.ToLower(string(text))
+		}
+	}
+	return strings.ToLower(fmt.Sprint(k.Interface()))
+}
+
+// toYamlValue converts value into a structure yaml.v2 marshals with config field names
+func toYamlValue(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Type().Implements(yamlMarshalerType) || v.Type().Implements(textMarshalerType) {
+		return v.Interface()
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return toYamlValue(v.Elem())
+	case reflect.Struct:
+		res := yaml.MapSlice{}
+		for _, f := range visibleFields(v.Type(), v) {
+			if !f.value.IsValid() || f.naming.OmitEmpty && f.value.IsZero() {
+				continue
+			}
+			res = append(res, yaml.MapItem{Key: f.naming.Name, Value: toYamlValue(f.value)})
+		}
+		return res
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		res := yaml.MapSlice{}
+		for _, k := range keys {
+			res = append(res, yaml.MapItem{Key: k.Interface(), Value: toYamlValue(v.MapIndex(k))})
+		}
+		return res
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		res := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			res = append(res, toYamlValue(v.Index(i)))
+		}
+		return res
+	}
+	return v.Interface()
+}
@@ -0,0 +1,107 @@
+package insconfig_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/soverenio/insconfig"
+)
+
+type NamingInner struct {
+	Port int `yaml:"port_number"`
+}
+
+type NamingLimits struct {
+	MaxConns int `mapstructure:"max_conns"`
+}
+
+type NamingCfg struct {
+	ListenAddr  string `mapstructure:"listen_addr"`
+	LogLevel    string `yaml:"log_level,omitempty"`
+	Ignored     string `yaml:"-"`
+	NamingInner `yaml:",inline"`
+	Limits      NamingLimits
+}
+
+func Test_Naming(t *testing.T) {
+	load := func(t *testing.T) (NamingCfg, insconfig.Params, error) {
+		cfg := NamingCfg{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{"testdata/test_config_naming.yaml"},
+		}
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		return cfg, params, err
+	}
+
+	t.Run("load", func(t *testing.T) {
+		cfg, _, err := load(t)
+		require.NoError(t, err)
+		require.Equal(t, "localhost:8080", cfg.ListenAddr)
+		require.Equal(t, "info", cfg.LogLevel)
+		require.Equal(t, 80, cfg.Port)
+		require.Equal(t, 10, cfg.Limits.MaxConns)
+	})
+
+	t.Run("env", func(t *testing.T) {
+		_ = os.Setenv("TESTPREFIX_LISTEN_ADDR", "0.0.0.0:80")
+		defer os.Unsetenv("TESTPREFIX_LISTEN_ADDR")
+		_ = os.Setenv("TESTPREFIX_LIMITS_MAX_CONNS", "20")
+		defer os.Unsetenv("TESTPREFIX_LIMITS_MAX_CONNS")
+		_ = os.Setenv("TESTPREFIX_PORT_NUMBER", "81")
+		defer os.Unsetenv("TESTPREFIX_PORT_NUMBER")
+
+		cfg, _, err := load(t)
+		require.NoError(t, err)
+		require.Equal(t, "0.0.0.0:80", cfg.ListenAddr)
+		require.Equal(t, 20, cfg.Limits.MaxConns)
+		require.Equal(t, 81, cfg.Port)
+	})
+
+	t.Run("fail wrong type in renamed field", func(t *testing.T) {
+		_ = os.Setenv("TESTPREFIX_LIMITS_MAX_CONNS", "many")
+		defer os.Unsetenv("TESTPREFIX_LIMITS_MAX_CONNS")
+
+		_, _, err := load(t)
+		var decodeErr *insconfig.DecodeError
+		require.True(t, errors.As(err, &decodeErr), err)
+		require.Equal(t, "limits.max_conns", decodeErr.Key)
+		require.Equal(t, insconfig.Source{Kind: insconfig.SourceEnv, Name: "TESTPREFIX_LIMITS_MAX_CONNS"}, decodeErr.Source)
+	})
+
+	t.Run("template", func(t *testing.T) {
+		w := &bytes.Buffer{}
+		err := insconfig.NewYamlTemplaterStruct(NamingCfg{}).TemplateTo(w)
+		require.NoError(t, err)
+		require.Contains(t, w.String(), "listen_addr: ")
+		require.Contains(t, w.String(), "log_level: ")
+		require.Contains(t, w.String(), "\nport_number: ")
+		require.Contains(t, w.String(), "  max_conns: ")
+		require.NotContains(t, w.String(), "ignored")
+	})
+
+	t.Run("dump", func(t *testing.T) {
+		cfg := NamingCfg{ListenAddr: "localhost:8080", NamingInner: NamingInner{Port: 80}, Ignored: "value"}
+		w := &bytes.Buffer{}
+		err := insconfig.NewYamlDumper(cfg).DumpTo(w)
+		require.NoError(t, err)
+		require.Contains(t, w.String(), "listen_addr: localhost:8080\n")
+		require.Contains(t, w.String(), "\nport_number: 80\n")
+		require.Contains(t, w.String(), "  max_conns: 0\n")
+		require.NotContains(t, w.String(), "log_level")
+		require.NotContains(t, w.String(), "ignored")
+
+		insConfigurator := insconfig.New(insconfig.Params{ConfigPathGetter: testPathGetter{""}})
+		out := insConfigurator.ToYaml(cfg)
+		require.Contains(t, out, "listen_addr: localhost:8080\n")
+		require.Contains(t, out, "\nport_number: 80\n")
+		require.Contains(t, out, "  max_conns: 0\n")
+		require.NotContains(t, out, "log_level")
+		require.NotContains(t, out, "ignored")
+	})
+}
@@ -0,0 +1,61 @@
+package insconfig
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SourceKind is a kind of place where config value came from
+type SourceKind string
+
+const (
+	// SourceFile - value is set in config file
+	SourceFile SourceKind = "file"
+	// SourceEnv - value is set in environment variable
+	SourceEnv SourceKind = "env"
+	// SourceDefault - value is not set, the default from insconfig tag is used
+	SourceDefault SourceKind = "default"
+)
+
+// Source describes where config value came from
+type Source struct {
+	Kind SourceKind
+	// Name is a file path for SourceFile and a variable name for SourceEnv, empty for SourceDefault
+	Name string
+	// Line and Column of the value in file, set for SourceFile only
+	Line   int
+	Column int
+}
+
+func (s Source) String() string {
+	if s.Kind == SourceFile && s.Line > 0 {
+		return fmt.Sprintf("%s %s:%d:%d", s.Kind, s.Name, s.Line, s.Column)
+	}
+	if s.Name == "" {
+		return string(s.Kind)
+	}
+	return fmt.Sprintf("%s %s", s.Kind, s.Name)
+}
+
+// Provenance returns sources of the config values loaded by Load, keys are lowercased and dot separated
+// the same way as in error messages, e.g. "hostnetwork.mintimeout"
+func (i *insConfigurator) Provenance() map[string]Source {
+	res := make(map[string]Source, len(i.sources))
+	for k, v := range i.sources {
+		res[k] = v
+	}
+	return res
+}
+
+// collectSources finds the source of every config key, ENV sources are collected while checking ENV values
+func (i *insConfigurator) collectSources(structKeys []string) {
+	for _, key := range structKeys {
+		key = strings.ToLower(key)
+		if _, ok := i.sources[key]; ok {
+			continue
+		}
+		if file, pos, ok := i.lastFileWithKey(key); ok {
+			i.sources[key] = Source{Kind: SourceFile, Name: file.path, Line: pos.Line, Column: pos.Column}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package insconfig
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Fields are restart-required by default, use insconfigreload tag to mark a field (with all nested fields)
+// as safe to change on the fly:
+//     LogLevel string `insconfigreload:""`
+
+// reloadValue is a leaf config value with its reload policy
+type reloadValue struct {
+	value      interface{}
+	reloadable bool
+}
+
+// checkReloadable returns error listing keys that can't be changed without restart
+func checkReloadable(oldConfig, newConfig interface{}) error {
+	oldValues := make(map[string]reloadValue)
+	deepFieldValues(oldConfig, "", false, oldValues)
+	newValues := make(map[string]reloadValue)
+	deepFieldValues(newConfig, "", false, newValues)
+
+	var errorKeys []string
+	for key, oldValue := range oldValues {
+		newValue, ok := newValues[key]
+		if ok && reflect.DeepEqual(oldValue.value, newValue.value) {
+			continue
+		}
+		if !oldValue.reloadable {
+			errorKeys = append(errorKeys, key)
+		}
+	}
+	for key, newValue := range newValues {
+		if _, ok := oldValues[key]; !ok && !newValue.reloadable {
+			errorKeys = append(errorKeys, key)
+		}
+	}
+
+	if len(errorKeys) > 0 {
+		sort.Strings(errorKeys)
+		return errors.New(fmt.Sprintf("Keys can't be changed without restart: %s", strings.Join(errorKeys, ", ")))
+	}
+	return nil
+}
+
+// deepFieldValues collects leaf values with keys built the same way as in deepFieldNames
+func deepFieldValues(iface interface{}, prefix string, reloadable bool, values map[string]reloadValue) {
+	ifv := reflect.Indirect(reflect.ValueOf(iface))
+
+	switch ifv.Kind() {
+	case reflect.Struct:
+		for i := 0; i < ifv.Type().NumField(); i++ {
+			field := ifv.Type().Field(i)
+			_, fieldReloadable := field.Tag.Lookup("insconfigreload")
+
+			newPrefix, ok := fieldKey(prefix, field)
+			if !ok {
+				continue
+			}
+			deepFieldValues(ifv.Field(i).Interface(), newPrefix, reloadable || fieldReloadable, values)
+		}
+	case reflect.Map:
+		for _, k := range ifv.MapKeys() {
+			deepFieldValues(ifv.MapIndex(k).Interface(), joinKey(prefix, mapKeyString(k)), reloadable, values)
+		}
+	case reflect.Invalid:
+		if prefix != "" {
+			values[prefix] = reloadValue{value: nil, reloadable: reloadable}
+		}
+	default:
+		if prefix != "" {
+			values[prefix] = reloadValue{value: ifv.Interface(), reloadable: reloadable}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package insconfig
+
+import (
+	"strings"
+)
+
+// maxSuggestDistance limits edit distance of a suggested key, longer keys may have more typos
+const maxSuggestDistance = 3
+
+// suggestKey returns the closest to key candidate or an empty string if there is no close enough one.
+// Map placeholders of candidates are replaced by the corresponding parts of key
+func suggestKey(key string, candidates []string) string {
+	limit := len(key) / 3
+	if limit > maxSuggestDistance {
+		limit = maxSuggestDistance
+	}
+	best, bestDistance := "", limit+1
+	for _, candidate := range candidates {
+		candidate = fillPlaceholders(candidate, key)
+		if candidate == key {
+			continue
+		}
+		if d := editDistance(key, candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// fillPlaceholders replaces placeholders of candidate by parts of key with the same position
+func fillPlaceholders(candidate, key string) string {
+	if !strings.Contains(candidate, placeholder) {
+		return candidate
+	}
+	candidateParts := strings.Split(candidate, ".")
+	keyParts := strings.Split(key, ".")
+	for i, part := range candidateParts {
+		if part == placeholder && i < len(keyParts) {
+			candidateParts[i] = keyParts[i]
+		}
+	}
+	return strings.Join(candidateParts, ".")
+}
+
+// editDistance is Levenshtein distance of two strings
+func editDistance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	prev := make([]int, len(br)+1)
+	cur := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		cur[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1
+			}
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(br)]
+}
@@ -0,0 +1,280 @@
+package insconfig
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/soverenio/insconfig/utils"
+)
+
+// Declarative validation of config values
+// you may use insconfigvalidate tag with comma separated rules on struct fields:
+//     Address    string        `insconfigvalidate:"required,hostport"`
+//     MinTimeout time.Duration `insconfigvalidate:"min=1s,max=1m"`
+//     Level      string        `insconfigvalidate:"oneof=debug info warn error"`
+//     Name       string        `insconfigvalidate:"regexp=^[a-z]+(,[a-z]+)*$"`
+// Supported rules:
+//   required     value is not zero (not empty string, not nil, not 0)
+//   min=N, max=N value bounds for numbers and durations, length bounds for strings, slices and maps
+//   oneof=a b c  value is one of the space separated values
+//   regexp=RE    value matches regular expression, this rule takes the rest of the tag, so it must be the last one
+//   hostport     value is "host:port"
+//   url          value is an absolute URL
+//   file-exists  value is a path to an existing file or directory
+// Rules are checked after all the values are loaded, all the failures are returned in a single error.
+
+const validateTag = "insconfigvalidate"
+
+// Validator - implement this on your config types for custom and cross-field checks.
+// Validate is called after all the values are loaded on the config itself and on every nested struct,
+// map value and slice element implementing it, nested values are validated first.
+// A struct embedding a Validator is validated by the embedded one only, its Validate is assumed to be promoted
+type Validator interface {
+	Validate() error
+}
+
+var validatorType = reflect.TypeOf((*Validator)(nil)).Elem()
+
+// validateValues checks insconfigvalidate rules of all the fields and calls Validate of all the Validators
+func validateValues(configStruct interface{}) error {
+	var failures []*FieldError
+	if err := deepValidate(reflect.ValueOf(configStruct), "", ".", &failures); err != nil {
+		return err
+	}
+	if len(failures) > 0 {
+		return &ValidationError{Errors: failures}
+	}
+	return nil
+}
+
+func deepValidate(v reflect.Value, prefix string, path utils.Path, failures *[]*FieldError) error {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return nil
+	}
+	v = reflect.Indirect(v)
+
+	// Validate of a struct embedding a validated Validator is considered promoted, it's called on the embedded field
+	promoted := false
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			key, ok := fieldKey(prefix, field)
+			if !ok {
+				continue
+			}
+			if field.Anonymous && (field.Type.Implements(validatorType) || reflect.PtrTo(field.Type).Implements(validatorType)) {
+				promoted = true
+			}
+
+			if rules, ok := field.Tag.Lookup(validateTag); ok {
+				fieldPath := path.AppendStructKey(field.Name)
+				ruleFailures, err := checkRules(v.Field(i), rules)
+				if err != nil {
+					return errors.Wrapf(err, "wrong %s tag of field %s", validateTag, key)
+				}
+				for _, failure := range ruleFailures {
+					failure.Key, failure.Path = key, fieldPath
+					*failures = append(*failures, failure)
+				}
+			}
+
+			if err := deepValidate(v.Field(i), key, path.AppendStructKey(field.Name), failures); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			if err := deepValidate(v.MapIndex(k), joinKey(prefix, mapKeyString(k)), path.AppendMapKey(fmt.Sprint(k.Interface())), failures); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := deepValidate(v.Index(i), joinKey(prefix, strconv.Itoa(i)), path.AppendArrayIdx(i), failures); err != nil {
+				return err
+			}
+		}
+	}
+
+	if promoted {
+		return nil
+	}
+	if err := callValidate(v); err != nil {
+		*failures = append(*failures, &FieldError{Key: prefix, Path: path, err: err})
+	}
+	return nil
+}
+
+// callValidate calls Validate if value implements Validator by value or by pointer
+func callValidate(v reflect.Value) error {
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Type().Implements(validatorType) {
+		if v.Kind() == reflect.Interface && v.IsNil() {
+			return nil
+		}
+		return v.Interface().(Validator).Validate()
+	}
+	if !reflect.PtrTo(v.Type()).Implements(validatorType) {
+		return nil
+	}
+	if !v.CanAddr() {
+		// map values are not addressable
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		v = ptr.Elem()
+	}
+	return v.Addr().Interface().(Validator).Validate()
+}
+
+// splitRules splits tag by commas, regexp rule takes the rest of the tag
+func splitRules(rules string) []string {
+	var res []string
+	for rules != "" {
+		if strings.HasPrefix(rules, "regexp=") {
+			return append(res, rules)
+		}
+		parts := strings.SplitN(rules, ",", 2)
+		res = append(res, strings.TrimSpace(parts[0]))
+		if len(parts) == 1 {
+			break
+		}
+		rules = strings.TrimLeft(parts[1], " ")
+	}
+	return res
+}
+
+// checkRules returns failures with Rule set, error is returned for malformed rules only
+func checkRules(v reflect.Value, rules string) ([]*FieldError, error) {
+	var failures []*FieldError
+	for _, rule := range splitRules(rules) {
+		if rule == "" {
+			continue
+		}
+		name, arg := rule, ""
+		if parts := strings.SplitN(rule, "=", 2); len(parts) == 2 {
+			name, arg = parts[0], parts[1]
+		}
+
+		if name == "required" {
+			if !v.IsValid() || v.IsZero() {
+				failures = append(failures, &FieldError{Rule: rule, err: errors.New("is required")})
+			}
+			continue
+		}
+
+		value := reflect.Indirect(v)
+		if !value.IsValid() {
+			// nil pointers are checked by "required" only
+			continue
+		}
+
+		msg, err := checkRule(value, name, arg)
+		if err != nil {
+			return nil, err
+		}
+		if msg != "" {
+			failures = append(failures, &FieldError{Rule: rule, err: errors.New(msg)})
+		}
+	}
+	return failures, nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func checkRule(v reflect.Value, name, arg string) (string, error) {
+	switch name {
+	case "min", "max":
+		actual, bound, err := compareValues(v, arg)
+		if err != nil {
+			return "", err
+		}
+		if name == "min" && actual < bound {
+			return fmt.Sprintf("must be at least %s, got %v", arg, formatActual(v)), nil
+		}
+		if name == "max" && actual > bound {
+			return fmt.Sprintf("must be at most %s, got %v", arg, formatActual(v)), nil
+		}
+	case "oneof":
+		value := fmt.Sprint(v.Interface())
+		for _, allowed := range strings.Fields(arg) {
+			if value == allowed {
+				return "", nil
+			}
+		}
+		return fmt.Sprintf("must be one of [%s], got %q", arg, value), nil
+	case "regexp":
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return "", errors.Wrapf(err, "wrong regexp")
+		}
+		if value := fmt.Sprint(v.Interface()); !re.MatchString(value) {
+			return fmt.Sprintf("must match %s, got %q", arg, value), nil
+		}
+	case "hostport":
+		value := fmt.Sprint(v.Interface())
+		if _, port, err := net.SplitHostPort(value); err != nil || port == "" {
+			return fmt.Sprintf("must be host:port, got %q", value), nil
+		}
+	case "url":
+		value := fmt.Sprint(v.Interface())
+		if u, err := url.Parse(value); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Sprintf("must be an absolute URL, got %q", value), nil
+		}
+	case "file-exists":
+		value := fmt.Sprint(v.Interface())
+		if _, err := os.Stat(value); err != nil {
+			return fmt.Sprintf("must be an existing file, got %q", value), nil
+		}
+	default:
+		return "", errors.New(fmt.Sprintf("unknown rule %q", name))
+	}
+	return "", nil
+}
+
+// compareValues returns comparable representations of the value and the bound,
+// durations are compared as durations, numbers as numbers, strings, slices and maps by length
+func compareValues(v reflect.Value, bound string) (float64, float64, error) {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(bound)
+		if err != nil {
+			return 0, 0, errors.Wrapf(err, "wrong duration bound")
+		}
+		return float64(v.Int()), float64(d), nil
+	}
+
+	b, err := strconv.ParseFloat(bound, 64)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "wrong bound")
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), b, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), b, nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), b, nil
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), b, nil
+	}
+	return 0, 0, errors.New(fmt.Sprintf("min and max are not supported for %s", v.Type()))
+}
+
+func formatActual(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return fmt.Sprintf("length %d", v.Len())
+	}
+	return v.Interface()
+}
@@ -0,0 +1,186 @@
+package insconfig_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/soverenio/insconfig"
+)
+
+type ValidatedClient struct {
+	Address string `insconfigvalidate:"required,hostport"`
+}
+
+type ValidatedCfg struct {
+	Address string                     `insconfigvalidate:"required,hostport"`
+	URL     string                     `insconfigvalidate:"url"`
+	Level   string                     `insconfigvalidate:"oneof=debug info warn error"`
+	Name    string                     `insconfigvalidate:"regexp=^[a-z]+(,[a-z]+)*$"`
+	Timeout time.Duration              `insconfigvalidate:"min=1s,max=1m"`
+	Retries int                        `insconfigvalidate:"min=1"`
+	Tags    []string                   `insconfigvalidate:"min=1"`
+	DataDir string                     `insconfigvalidate:"file-exists"`
+	Clients map[string]ValidatedClient `insconfigvalidate:"max=2"`
+}
+
+type ValidatorClient struct {
+	Address string
+}
+
+func (c ValidatorClient) Validate() error {
+	if c.Address == "" {
+		return errors.New("address is empty")
+	}
+	return nil
+}
+
+type ValidatorNetwork struct {
+	MinTimeout int
+	MaxTimeout int
+}
+
+func (n *ValidatorNetwork) Validate() error {
+	if n.MinTimeout > n.MaxTimeout {
+		return errors.New("mintimeout is greater than maxtimeout")
+	}
+	return nil
+}
+
+type ValidatorCfg struct {
+	HostNetwork ValidatorNetwork
+	Clients     map[string]ValidatorClient
+	Peers       []ValidatorClient
+}
+
+func (c *ValidatorCfg) Validate() error {
+	if len(c.Clients) == 0 {
+		return errors.New("no clients")
+	}
+	return nil
+}
+
+type SquashedValidatorCfg struct {
+	ValidatorNetwork `mapstructure:",squash"`
+	Name             string
+}
+
+type SkippedValidatorCfg struct {
+	ValidatorNetwork `mapstructure:"-"`
+	Name             string
+}
+
+func Test_Validation(t *testing.T) {
+	t.Run("happy", func(t *testing.T) {
+		cfg := ValidatedCfg{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{"testdata/test_config_validation_ok.yaml"},
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		require.NoError(t, err)
+		require.Equal(t, 10*time.Second, cfg.Timeout)
+	})
+
+	t.Run("fail all rules", func(t *testing.T) {
+		cfg := ValidatedCfg{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{"testdata/test_config_validation.yaml"},
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "Keys failed validation")
+		require.Contains(t, err.Error(), `address must be host:port, got "localhost"`)
+		require.Contains(t, err.Error(), `url must be an absolute URL, got "not a url"`)
+		require.Contains(t, err.Error(), `level must be one of [debug info warn error], got "verbose"`)
+		require.Contains(t, err.Error(), `name must match ^[a-z]+(,[a-z]+)*$, got "Bad Name"`)
+		require.Contains(t, err.Error(), "timeout must be at least 1s, got 100ms")
+		require.Contains(t, err.Error(), "retries must be at least 1, got 0")
+		require.Contains(t, err.Error(), "tags must be at least 1, got length 0")
+		require.Contains(t, err.Error(), `datadir must be an existing file, got "testdata/nonexistent"`)
+		require.Contains(t, err.Error(), "clients.second.address is required")
+		require.NotContains(t, err.Error(), "clients.first.address")
+	})
+
+	t.Run("fail wrong tag", func(t *testing.T) {
+		_ = os.Setenv("TESTPREFIX_ADDRESS", "localhost:8080")
+		defer os.Unsetenv("TESTPREFIX_ADDRESS")
+
+		type WrongTag struct {
+			Address string `insconfigvalidate:"nonexistent"`
+		}
+		cfg := WrongTag{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{""},
+			FileNotRequired:  true,
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `wrong insconfigvalidate tag of field address: unknown rule "nonexistent"`)
+	})
+
+	t.Run("validator", func(t *testing.T) {
+		cfg := ValidatorCfg{}
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{"testdata/test_config_validator.yaml"},
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), ".HostNetwork: mintimeout is greater than maxtimeout")
+		require.Contains(t, err.Error(), ".Clients['second']: address is empty")
+		require.Contains(t, err.Error(), ".Peers[1]: address is empty")
+		require.NotContains(t, err.Error(), "first")
+		require.NotContains(t, err.Error(), "no clients")
+	})
+	t.Run("validator of squashed struct", func(t *testing.T) {
+		_ = os.Setenv("TESTPREFIX_MINTIMEOUT", "10")
+		_ = os.Setenv("TESTPREFIX_MAXTIMEOUT", "5")
+		_ = os.Setenv("TESTPREFIX_NAME", "")
+		defer os.Unsetenv("TESTPREFIX_MINTIMEOUT")
+		defer os.Unsetenv("TESTPREFIX_MAXTIMEOUT")
+		defer os.Unsetenv("TESTPREFIX_NAME")
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{""},
+			FileNotRequired:  true,
+		}
+
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&SquashedValidatorCfg{})
+		var validationErr *insconfig.ValidationError
+		require.True(t, errors.As(err, &validationErr), err)
+		require.EqualError(t, err, "Keys failed validation: .ValidatorNetwork: mintimeout is greater than maxtimeout")
+	})
+
+	t.Run("validator of skipped embedded struct", func(t *testing.T) {
+		_ = os.Setenv("TESTPREFIX_NAME", "name")
+		defer os.Unsetenv("TESTPREFIX_NAME")
+		params := insconfig.Params{
+			EnvPrefix:        "testprefix",
+			ConfigPathGetter: testPathGetter{""},
+			FileNotRequired:  true,
+		}
+
+		// the embedded field is not loaded, so promoted Validate is called on the parent
+		cfg := SkippedValidatorCfg{ValidatorNetwork: ValidatorNetwork{MinTimeout: 10, MaxTimeout: 5}}
+		insConfigurator := insconfig.New(params)
+		err := insConfigurator.Load(&cfg)
+		var validationErr *insconfig.ValidationError
+		require.True(t, errors.As(err, &validationErr), err)
+		require.EqualError(t, err, "Keys failed validation: .: mintimeout is greater than maxtimeout")
+	})
+}
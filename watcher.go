@@ -0,0 +1,231 @@
+package insconfig
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// reloadDelay groups several file events (e.g. truncate and write) into a single reload
+const reloadDelay = 100 * time.Millisecond
+
+// Watcher reloads configuration when config files change.
+// Every reload runs the whole Load pipeline into a fresh config structure,
+// the current config is replaced only if all the checks pass, so a broken file never gets into the app
+type Watcher struct {
+	params      Params
+	configPaths []string
+	configType  reflect.Type
+	fsWatcher   *fsnotify.Watcher
+	done        chan struct{}
+	stopped     chan struct{}
+	closeOnce   sync.Once
+	closeErr    error
+
+	mu          sync.RWMutex
+	current     interface{}
+	files       []configFile
+	subscribers []func(oldConfig, newConfig interface{})
+	onError     []func(err error)
+}
+
+// Watch starts watching config files and reloading configuration on their change.
+// configStruct is a pointer to your config already loaded by Load, it's never modified by Watcher,
+// use Config to get the current config and Subscribe to get notified about changes.
+// Reloaded configs are decoded into a zero value of the config type.
+// Only fields with insconfigreload tag may change, a reload changing any other field is rejected
+func (i *insConfigurator) Watch(configStruct interface{}) (*Watcher, error) {
+	t := reflect.TypeOf(configStruct)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return nil, errors.New("configStruct should be a pointer")
+	}
+	if len(i.files) == 0 {
+		return nil, errors.New("no config files loaded, call Load before Watch")
+	}
+
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create config files watcher")
+	}
+
+	w := &Watcher{
+		params:      i.params,
+		configPaths: i.configPaths,
+		configType:  t.Elem(),
+		fsWatcher:   fsWatcher,
+		done:        make(chan struct{}),
+		stopped:     make(chan struct{}),
+		current:     configStruct,
+		files:       i.files,
+	}
+	if err := w.addWatches(); err != nil {
+		_ = fsWatcher.Close()
+		return nil, err
+	}
+
+	go w.run()
+	return w, nil
+}
+
+// Config returns the current config, it's a pointer of the same type as passed to Watch.
+// Returned config must not be modified
+func (w *Watcher) Config() interface{} {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.current
+}
+
+// Subscribe adds a callback called after every successful reload with the previous and the new configs
+func (w *Watcher) Subscribe(fn func(oldConfig, newConfig interface{})) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subscribers = append(w.subscribers, fn)
+}
+
+// OnError adds a callback called on every failed reload, the current config stays unchanged in this case
+func (w *Watcher) OnError(fn func(err error)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onError = append(w.onError, fn)
+}
+
+// Close stops watching, it may be called several times
+func (w *Watcher) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.done)
+		w.closeErr = w.fsWatcher.Close()
+		<-w.stopped
+	})
+	return w.closeErr
+}
+
+// Reload loads configuration again and replaces the current one if all the checks pass.
+// Usually it's called on config files change, but you may call it manually, e.g. on SIGHUP
+func (w *Watcher) Reload() error {
+	configurator := insConfigurator{
+		params:      w.params,
+		configPaths: w.configPaths,
+		viper:       viper.New(),
+	}
+	newConfig := reflect.New(w.configType).Interface()
+	if err := configurator.Load(newConfig); err != nil {
+		err = errors.Wrap(err, "failed to reload config")
+		w.notifyError(err)
+		return err
+	}
+
+	w.mu.Lock()
+	oldConfig := w.current
+	if err := checkReloadable(oldConfig, newConfig); err != nil {
+		w.mu.Unlock()
+		err = errors.Wrap(err, "failed to reload config")
+		w.notifyError(err)
+		return err
+	}
+	w.current = newConfig
+	w.files = configurator.files
+	subscribers := append([]func(oldConfig, newConfig interface{}){}, w.subscribers...)
+	w.mu.Unlock()
+
+	// included files may have changed
+	if err := w.addWatches(); err != nil {
+		w.notifyError(err)
+	}
+	for _, fn := range subscribers {
+		fn(oldConfig, newConfig)
+	}
+	return nil
+}
+
+func (w *Watcher) notifyError(err error) {
+	w.mu.RLock()
+	onError := append([]func(err error){}, w.onError...)
+	w.mu.RUnlock()
+	for _, fn := range onError {
+		fn(err)
+	}
+}
+
+// watchedDirs returns directories of config files, files are replaced by editors and k8s,
+// so it's more reliable to watch directories
+func (w *Watcher) watchedDirs() []string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	var dirs []string
+	for _, f := range w.files {
+		dirs = append(dirs, filepath.Dir(f.path))
+	}
+	for _, path := range w.configPaths {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			dirs = append(dirs, path)
+		}
+	}
+	return dirs
+}
+
+func (w *Watcher) addWatches() error {
+	for _, dir := range w.watchedDirs() {
+		if err := w.fsWatcher.Add(dir); err != nil {
+			return errors.Wrapf(err, "failed to watch config directory %s", dir)
+		}
+	}
+	return nil
+}
+
+// isConfigEvent checks whether event affects loaded config files
+func (w *Watcher) isConfigEvent(event fsnotify.Event) bool {
+	// k8s ConfigMap volumes swap "..data" symlink on update
+	if strings.HasPrefix(filepath.Base(event.Name), "..") {
+		return true
+	}
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	for _, f := range w.files {
+		if samePath(f.path, event.Name) {
+			return true
+		}
+	}
+	for _, path := range w.configPaths {
+		if samePath(path, filepath.Dir(event.Name)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *Watcher) run() {
+	defer close(w.stopped)
+
+	timer := time.NewTimer(reloadDelay)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case event, ok := <-w.fsWatcher.Events:
+			if !ok {
+				return
+			}
+			if w.isConfigEvent(event) {
+				timer.Reset(reloadDelay)
+			}
+		case err, ok := <-w.fsWatcher.Errors:
+			if !ok {
+				return
+			}
+			w.notifyError(errors.Wrap(err, "config files watcher failed"))
+		case <-timer.C:
+			_ = w.Reload()
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package insconfig_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/soverenio/insconfig"
+)
+
+type ReloadableCfg struct {
+	Level1text string `insconfigreload:""`
+	Level2     Level2
+}
+
+const reloadableConfig = `level1text: %s
+level2:
+  level2text: %s
+  level3:
+    level3text: text3
+    nullstring: null
+`
+
+func writeReloadableConfig(t *testing.T, path, level1text, level2text string) {
+	content := []byte(fmt.Sprintf(reloadableConfig, level1text, level2text))
+	require.NoError(t, ioutil.WriteFile(path, content, 0600))
+}
+
+func Test_Watch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeReloadableConfig(t, path, "initial", "text2")
+
+	cfg := ReloadableCfg{}
+	params := insconfig.Params{
+		EnvPrefix:        "testprefix",
+		ConfigPathGetter: testPathGetter{path},
+	}
+	insConfigurator := insconfig.New(params)
+	require.NoError(t, insConfigurator.Load(&cfg))
+
+	watcher, err := insConfigurator.Watch(&cfg)
+	require.NoError(t, err)
+	defer watcher.Close()
+
+	type change struct {
+		oldConfig, newConfig *ReloadableCfg
+	}
+	changes := make(chan change, 10)
+	errs := make(chan error, 10)
+	watcher.Subscribe(func(oldConfig, newConfig interface{}) {
+		changes <- change{oldConfig.(*ReloadableCfg), newConfig.(*ReloadableCfg)}
+	})
+	watcher.OnError(func(err error) {
+		errs <- err
+	})
+
+	t.Run("reload on change", func(t *testing.T) {
+		writeReloadableConfig(t, path, "changed", "text2")
+
+		select {
+		case c := <-changes:
+			require.Equal(t, "initial", c.oldConfig.Level1text)
+			require.Equal(t, "changed", c.newConfig.Level1text)
+		case err := <-errs:
+			require.NoError(t, err)
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "config is not reloaded")
+		}
+		require.Equal(t, "changed", watcher.Config().(*ReloadableCfg).Level1text)
+		require.Equal(t, "initial", cfg.Level1text)
+	})
+
+	t.Run("keep config on failed reload", func(t *testing.T) {
+		require.NoError(t, ioutil.WriteFile(path, []byte("level1text: broken\nnonexistent: value\n"), 0600))
+
+		select {
+		case c := <-changes:
+			require.FailNow(t, "broken config is applied", c.newConfig.Level1text)
+		case err := <-errs:
+			require.Contains(t, err.Error(), "failed to reload config")
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "reload error is not reported")
+		}
+		require.Equal(t, "changed", watcher.Config().(*ReloadableCfg).Level1text)
+	})
+
+	t.Run("reject change of not reloadable field", func(t *testing.T) {
+		writeReloadableConfig(t, path, "changed again", "changed")
+
+		select {
+		case c := <-changes:
+			require.FailNow(t, "not reloadable field is changed", c.newConfig.Level2.Level2text)
+		case err := <-errs:
+			require.Contains(t, err.Error(), "Keys can't be changed without restart: level2.level2text")
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "reload error is not reported")
+		}
+		require.Equal(t, "changed", watcher.Config().(*ReloadableCfg).Level1text)
+		require.Equal(t, "text2", watcher.Config().(*ReloadableCfg).Level2.Level2text)
+	})
+	t.Run("close twice", func(t *testing.T) {
+		require.NoError(t, watcher.Close())
+		require.NoError(t, watcher.Close())
+	})
+}
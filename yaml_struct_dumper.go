@@ -70,16 +70,11 @@ func writeComment(w io.Writer, indent string, tag reflect.StructTag) error {
 }
 
 func writeName(w io.Writer, indent string, name string, parentTag reflect.StructTag, objectType reflect.Type) error {
-	nameOverride, ok := parentTag.Lookup("yaml")
-	if ok {
-		name = nameOverride
-	}
-
 	if name == "" || objectType.Kind() == reflect.Array {
 		return nil
 	}
 
-	if _, err := fmt.Fprintf(w, "%s%s: ", indent, strings.ToLower(name)); err != nil {
+	if _, err := fmt.Fprintf(w, "%s%s: ", indent, namingFromTag(name, parentTag).Name); err != nil {
 		return throw.W(err, "failed to write field name")
 	}
 	return nil
@@ -109,7 +104,11 @@ func (m YamlTemplaterStruct) TemplateTo(w io.Writer) (err error) {
 	)
 
 	if t.Kind() == reflect.Ptr {
-		m.Obj = v.Elem().Interface()
+		if v.IsNil() { // template of a nil pointer shows zero value fields
+			m.Obj = reflect.Zero(t.Elem()).Interface()
+		} else {
+			m.Obj = v.Elem().Interface()
+		}
 		return m.TemplateTo(w)
 	}
 
@@ -128,10 +127,14 @@ func (m YamlTemplaterStruct) TemplateTo(w io.Writer) (err error) {
 				return err
 			}
 		}
-		for i := 0; i < t.NumField(); i++ {
-			fldType := t.Field(i)
+		for _, f := range visibleFields(t, v) {
+			fldType := f.field
+			obj := reflect.Zero(fldType.Type)
+			if f.value.IsValid() {
+				obj = f.value
+			}
 			childTemplater := YamlTemplaterStruct{
-				Obj:   v.Field(i).Interface(),
+				Obj:   obj.Interface(),
 				Level: m.Level + 1,
 				Tag:   fldType.Tag,
 				FName: fldType.Name,